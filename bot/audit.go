@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// audit decisions
+const (
+	AuditApproved = "approved"
+	AuditDenied   = "denied"
+	AuditRejected = "rejected"
+	// AuditRefill records a hot wallet refill from the reserve wallet
+	AuditRefill = "refill"
+	// AuditConfirmed and AuditFailed record the final state of an approved payout
+	AuditConfirmed = "confirmed"
+	AuditFailed    = "failed"
+)
+
+// AuditEntry is a single record of the payout audit log.
+// Every entry carries the hash of the previous one so any modification,
+// removal or reordering of entries breaks the chain. Simulated marks decisions
+// made in a dry run, for which nothing was sent.
+type AuditEntry struct {
+	Seq           uint64    `json:"seq"`
+	Time          time.Time `json:"time"`
+	Network       string    `json:"network,omitempty"`
+	Requester     string    `json:"requester"`
+	RequesterName string    `json:"requester_name,omitempty"`
+	Address       string    `json:"address,omitempty"`
+	Wallet        string    `json:"wallet,omitempty"`
+	Amount        uint64    `json:"amount,omitempty"`
+	Nonce         uint64    `json:"nonce,omitempty"`
+	TxID          string    `json:"tx_id,omitempty"`
+	Decision      string    `json:"decision"`
+	Reason        string    `json:"reason,omitempty"`
+	State         string    `json:"state,omitempty"`
+	Simulated     bool      `json:"simulated,omitempty"`
+	Prev          string    `json:"prev"`
+	Hash          string    `json:"hash"`
+}
+
+// computeHash returns the hex sha256 of the entry serialized without its own hash.
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog is an append only, hash chained log file of payout decisions.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	last string
+}
+
+// OpenAuditLog opens or creates the audit log at path and continues its chain.
+// An existing log is verified first so the bot never extends a broken chain.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	l := &AuditLog{}
+	if f, err := os.Open(path); err == nil {
+		last, err := VerifyAuditLog(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("existing audit log %v is invalid: %v", path, err)
+		}
+		if last != nil {
+			l.seq = last.Seq
+			l.last = last.Hash
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+// Append links e to the chain and writes it to disk. A nil log discards entries.
+func (l *AuditLog) Append(e *AuditEntry) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	e.Time = time.Now().UTC()
+	e.Prev = l.last
+	hash, err := e.computeHash()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry %v", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log %v", err)
+	}
+	l.seq = e.Seq
+	l.last = e.Hash
+	return nil
+}
+
+// Close closes the underlying file.
+func (l *AuditLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// VerifyAuditLog checks every entry of the log read from r and returns the last one.
+// It fails on modified entries, missing or reordered entries and broken links.
+func VerifyAuditLog(r io.Reader) (*AuditEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var last *AuditEntry
+	line := 0
+	for scanner.Scan() {
+		line++
+		e := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("line %v: malformed entry %v", line, err)
+		}
+
+		expectedSeq, expectedPrev := uint64(1), ""
+		if last != nil {
+			expectedSeq, expectedPrev = last.Seq+1, last.Hash
+		}
+		if e.Seq != expectedSeq {
+			return nil, fmt.Errorf("line %v: expected seq %v got %v, entries are missing or reordered", line, expectedSeq, e.Seq)
+		}
+		if e.Prev != expectedPrev {
+			return nil, fmt.Errorf("line %v: seq %v does not link to the previous entry", line, e.Seq)
+		}
+		hash, err := e.computeHash()
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if hash != e.Hash {
+			return nil, fmt.Errorf("line %v: seq %v hash mismatch, entry was modified", line, e.Seq)
+		}
+		last = e
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// audit records the outcome of a transfer request, submitted is true if the
+// transaction reached the node.
+func (b *botBackend) audit(e *AuditEntry, submitted bool, err error) {
+	switch {
+	case err == nil:
+		e.Decision = AuditApproved
+	case submitted:
+		e.Decision = AuditRejected
+		e.Reason = err.Error()
+	default:
+		e.Decision = AuditDenied
+		e.Reason = err.Error()
+	}
+	if err := b.auditLog.Append(e); err != nil {
+		logger.Error("failed to write audit log", zap.Error(err))
+	}
+}
+
+// SetAuditLog enables recording of payout decisions to l.
+func (b *botBackend) SetAuditLog(l *AuditLog) {
+	b.auditLog = l
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeAuditLog returns the lines of an audit log with n approved payouts.
+func writeAuditLog(t *testing.T, n int) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := l.Append(&AuditEntry{Requester: "alice", Address: testAddress, Amount: 100, Decision: AuditApproved}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// editAuditEntry applies edit to the entry of line, rehashing it if rehash is set.
+func editAuditEntry(t *testing.T, line string, rehash bool, edit func(e *AuditEntry)) string {
+	t.Helper()
+	e := &AuditEntry{}
+	if err := json.Unmarshal([]byte(line), e); err != nil {
+		t.Fatal(err)
+	}
+	edit(e)
+	if rehash {
+		hash, err := e.computeHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Hash = hash
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestVerifyAuditLogTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		want   string
+	}{
+		{"modified amount", func(lines []string) []string {
+			lines[1] = editAuditEntry(t, lines[1], false, func(e *AuditEntry) { e.Amount = 100000 })
+			return lines
+		}, "seq 2 hash mismatch"},
+		{"modified amount with its hash recomputed", func(lines []string) []string {
+			lines[1] = editAuditEntry(t, lines[1], true, func(e *AuditEntry) { e.Amount = 100000 })
+			return lines
+		}, "seq 3 does not link"},
+		{"deleted entry", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}, "expected seq 2 got 3"},
+		{"reordered entries", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, "expected seq 2 got 3"},
+		{"renumbered entries", func(lines []string) []string {
+			lines = append(lines[:1], lines[2:]...)
+			lines[1] = editAuditEntry(t, lines[1], true, func(e *AuditEntry) { e.Seq = 2 })
+			return lines
+		}, "seq 2 does not link"},
+		{"broken prev link", func(lines []string) []string {
+			lines[2] = editAuditEntry(t, lines[2], true, func(e *AuditEntry) { e.Prev = strings.Repeat("0", 64) })
+			return lines
+		}, "seq 3 does not link"},
+		{"malformed entry", func(lines []string) []string {
+			lines[1] = lines[1][:10]
+			return lines
+		}, "line 2: malformed entry"},
+	}
+	for _, tc := range tests {
+		lines := tc.tamper(writeAuditLog(t, 3))
+		_, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	last, err := VerifyAuditLog(strings.NewReader(strings.Join(writeAuditLog(t, 3), "\n")))
+	if err != nil || last.Seq != 3 {
+		t.Errorf("intact log: last = %+v, error = %v", last, err)
+	}
+}
+
+func TestOpenAuditLogRefusesBrokenChain(t *testing.T) {
+	lines := writeAuditLog(t, 2)
+	lines[0] = editAuditEntry(t, lines[0], false, func(e *AuditEntry) { e.Address = otherTestAddress })
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAuditLog(path); err == nil {
+		t.Error("broken audit log extended")
+	}
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"fmt"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const defaultBalanceCheckInterval = time.Minute
+
+type alertLevel int
+
+const (
+	levelOK alertLevel = iota
+	levelWarning
+	levelCritical
+)
+
+// BalanceMonitor watches the total balance of the faucet wallets and alerts
+// operators when it runs low.
+type BalanceMonitor struct {
+	backend   Client
+	addresses []gosmtypes.Address
+	notifier  Notifier
+	cfg       BaseConfig
+	level     alertLevel
+}
+
+func NewBalanceMonitor(backend Client, addresses []gosmtypes.Address, notifier Notifier, cfg BaseConfig) *BalanceMonitor {
+	return &BalanceMonitor{
+		backend:   backend,
+		addresses: addresses,
+		notifier:  notifier,
+		cfg:       cfg,
+		level:     levelOK,
+	}
+}
+
+// Enabled returns true if thresholds and at least one alert target are configured.
+func (m *BalanceMonitor) Enabled() bool {
+	hasThreshold := m.cfg.BalanceWarning > 0 || m.cfg.BalanceCritical > 0
+	hasTarget := m.cfg.AlertChannel != "" || len(m.cfg.AlertUsers) > 0
+	return hasThreshold && hasTarget
+}
+
+// Run checks the balance periodically until stop is closed.
+func (m *BalanceMonitor) Run(stop <-chan struct{}) {
+	interval := m.cfg.BalanceCheckInterval
+	if interval <= 0 {
+		interval = defaultBalanceCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.check()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+func (m *BalanceMonitor) check() {
+	var balance uint64
+	for _, address := range m.addresses {
+		state, err := m.backend.AccountState(address)
+		if err != nil {
+			logger.Warn("balance monitor: failed to read faucet account", zap.String("address", address.String()), zap.Error(err))
+			return
+		}
+		balance += state.StateProjected.Balance.Value
+	}
+
+	level := m.nextLevel(balance)
+	if level == m.level {
+		return
+	}
+	prev := m.level
+	m.level = level
+	NotifyOperators(m.notifier, m.cfg, m.alertText(prev, level, balance))
+}
+
+// nextLevel returns the alert level for balance. Once a level is reached the balance
+// has to clear its threshold by BalanceHysteresis before the level is lowered again,
+// so a balance hovering around a threshold does not produce a stream of alerts.
+func (m *BalanceMonitor) nextLevel(balance uint64) alertLevel {
+	level := levelOK
+	switch {
+	case balance < m.cfg.BalanceCritical:
+		level = levelCritical
+	case balance < m.cfg.BalanceWarning:
+		level = levelWarning
+	}
+	if level >= m.level {
+		return level
+	}
+
+	margin := m.cfg.BalanceHysteresis
+	if m.level == levelCritical && balance < m.cfg.BalanceCritical+margin {
+		return levelCritical
+	}
+	if m.cfg.BalanceWarning > 0 && balance < m.cfg.BalanceWarning+margin {
+		return levelWarning
+	}
+	return levelOK
+}
+
+func (m *BalanceMonitor) addressList() string {
+	var list []string
+	for _, address := range m.addresses {
+		list = append(list, address.String())
+	}
+	return strings.Join(list, "`, `")
+}
+
+func (m *BalanceMonitor) alertText(prev, level alertLevel, balance uint64) string {
+	switch level {
+	case levelCritical:
+		return fmt.Sprintf("🚨 **Critical:** faucet balance is %v (threshold %v).\nIt is necessary to replenish the faucet addresses: `%v`",
+			balance, m.cfg.BalanceCritical, m.addressList())
+	case levelWarning:
+		if prev == levelCritical {
+			return fmt.Sprintf("⚠️ Faucet balance recovered to %v but is still below %v.\nFaucet addresses: `%v`",
+				balance, m.cfg.BalanceWarning, m.addressList())
+		}
+		return fmt.Sprintf("⚠️ **Warning:** faucet balance is %v (threshold %v).\nPlease replenish the faucet addresses: `%v`",
+			balance, m.cfg.BalanceWarning, m.addressList())
+	}
+	return fmt.Sprintf("✅ Faucet balance is back to %v. All clear.", balance)
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNotifier records the alerts sent to the alert channel.
+type fakeNotifier struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (n *fakeNotifier) NotifyChannel(channelID string, msg string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.msgs = append(n.msgs, msg)
+	return nil
+}
+
+func (n *fakeNotifier) NotifyUser(userID string, msg string) error {
+	return nil
+}
+
+func TestBalanceMonitorHysteresis(t *testing.T) {
+	// each step is a balance and the alert it triggers, "" for none
+	type step struct {
+		balance uint64
+		alert   string
+	}
+	tests := []struct {
+		name    string
+		warning uint64
+		steps   []step
+	}{
+		{"no flapping inside the margin", 1000, []step{
+			{1050, ""}, {990, "Warning"}, {1010, ""}, {995, ""}, {1099, ""}, {1100, "All clear"}, {1050, ""},
+		}},
+		{"critical to ok is one all clear", 1000, []step{
+			{400, "Critical"}, {2000, "All clear"}, {2000, ""},
+		}},
+		{"critical recovers through warning", 1000, []step{
+			{400, "Critical"}, {550, ""}, {700, "recovered"}, {1050, ""}, {1200, "All clear"},
+		}},
+		{"warning drops to critical", 1000, []step{
+			{900, "Warning"}, {400, "Critical"}, {450, ""}, {900, "recovered"},
+		}},
+		{"no warning level", 0, []step{
+			{600, ""}, {450, "Critical"}, {520, ""}, {450, ""}, {600, "All clear"}, {50000, ""},
+		}},
+	}
+	for _, tc := range tests {
+		cfg := testConfig()
+		cfg.BalanceWarning = tc.warning
+		cfg.BalanceCritical = 500
+		cfg.BalanceHysteresis = 100
+		cfg.AlertChannel = "alerts"
+		wallet := testWallet(1).Address
+		client := newFakeClient()
+		notifier := &fakeNotifier{}
+		m := NewBalanceMonitor(client, []gosmtypes.Address{wallet}, notifier, cfg)
+
+		for i, s := range tc.steps {
+			client.setAccount(wallet, s.balance, 0, s.balance, 0)
+			sent := len(notifier.msgs)
+			m.check()
+			switch {
+			case s.alert == "" && len(notifier.msgs) != sent:
+				t.Errorf("%v: step %v balance %v: unexpected alert %q", tc.name, i, s.balance, notifier.msgs[sent])
+			case s.alert != "" && len(notifier.msgs) != sent+1:
+				t.Errorf("%v: step %v balance %v: %v alerts, want %q", tc.name, i, s.balance, len(notifier.msgs)-sent, s.alert)
+			case s.alert != "" && !strings.Contains(notifier.msgs[sent], s.alert):
+				t.Errorf("%v: step %v balance %v: alert %q, want %q", tc.name, i, s.balance, notifier.msgs[sent], s.alert)
+			}
+		}
+	}
+}
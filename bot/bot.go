@@ -2,19 +2,22 @@ package bot
 
 import (
 	"bytes"
-	"encoding/hex"
+	"context"
 	"fmt"
-	"github.com/bwmarrin/discordgo"
 	xdr "github.com/nullstyle/go-xdr/xdr3"
 	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/ed25519"
 	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/common/util"
+	"go.uber.org/zap"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type Client interface {
+// NodeClient is the node API used by the bot.
+type NodeClient interface {
 	NodeStatus() (*apitypes.NodeStatus, error)
 	AccountState(address gosmtypes.Address) (*apitypes.Account, error)
 	Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error)
@@ -22,6 +25,14 @@ type Client interface {
 	GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error)
 }
 
+// Client is a NodeClient that also streams transaction states.
+type Client interface {
+	NodeClient
+	// SubscribeTransactionState streams the state changes of a transaction until
+	// ctx is done or the stream fails, then the channel is closed.
+	SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error)
+}
+
 const DefaultTxAmount = 1000
 const TranserBackoffSeconds = 300
 
@@ -45,33 +56,103 @@ Transaction status explanation:
 
 5. '$balance <ADDRESS>' - show address balance
 
-6. '$dump_txs <ADDRESS>' - get json file with all transactions"""`
+6. '$dump_txs <ADDRESS>' - get json file with all transactions
+
+7. '$my_tier' - show your payout tier, amount, cooldown and daily cap
+
+8. '$recent_payouts' - show the latest payouts of the faucet
+
+9. '$networks' - list the networks served by the bot, add '--net <name>' to any command to select a network
+
+10. '$guild' - show the faucet settings of this server, admins change them with '$guild set <key> <value>'`
+
+const helpTextES = `**Lista de comandos disponibles:**
+1. Solicitar monedas del grifo - envía tu dirección
+*Puedes solicitar monedas como máximo una vez cada tres horas*
+
+Explicación del estado de las transacciones:
+💸 - el bot envió la transacción a tu dirección, pero aún no ha sido confirmada
+✅ - la transacción fue confirmada
+🚫 - la transacción no fue confirmada por algún motivo. Debes hacer otra solicitud
+*El bot sigue el estado de la transacción solo durante 15 minutos*
+*El tiempo medio de confirmación es de 10 a 13 minutos*
+
+2. '$faucet_status' - muestra el estado actual del nodo del grifo
+
+3. '$faucet_address' o '$tap_address' - muestra la dirección del grifo
+
+4. '$tx_info <TX_ID>' - muestra la información de una transacción
+(remitente, destinatario, comisión, cantidad, estado)
+
+5. '$balance <DIRECCIÓN>' - muestra el saldo de una dirección
+
+6. '$dump_txs <DIRECCIÓN>' - obtiene un archivo json con todas las transacciones
+
+7. '$my_tier' - muestra tu nivel de pago, cantidad, tiempo de espera y límite diario
+
+8. '$recent_payouts' - muestra los últimos pagos del grifo
+
+9. '$networks' - lista las redes del bot, añade '--net <nombre>' a cualquier comando para elegir una red
+
+10. '$guild' - muestra la configuración del grifo en este servidor, los administradores la cambian con '$guild set <clave> <valor>'`
+
+const defaultLanguage = "en"
+
+// helpTexts are the help texts by language.
+var helpTexts = map[string]string{
+	defaultLanguage: helpText,
+	"es":            helpTextES,
+}
+
+type handlerFunc func(req *Request) (*Response, error)
 
 type botBackend struct {
 	backend  Client
-	key      ed25519.PrivateKey
-	public   gosmtypes.Address
-	backoff  map[string]time.Time
-	handlers map[string]func(cmd []string) (string, error)
-	cfg      BaseConfig
+	wallets  []*Wallet
+	handlers map[string]handlerFunc
+	auditLog *AuditLog
+	tracker  *TxTracker
+	// now is the clock used for cooldowns and daily caps
+	now func() time.Time
+
+	// cfgMu guards cfg, which is replaced when the config is reloaded
+	cfgMu sync.RWMutex
+	cfg   BaseConfig
+
+	// walletMu guards wallet selection
+	walletMu sync.Mutex
+
+	// degraded is set while the connection to the node is down
+	degraded int32
+
+	// mu guards backoff, payouts, recent and the payout counters
+	mu        sync.Mutex
+	backoff   map[string]time.Time
+	payouts   map[string][]payout
+	recent    []TransferResult
+	confirmed uint64
+	failed    uint64
 }
 
-func NewBot(backend Client, publicKey gosmtypes.Address, key ed25519.PrivateKey, cfg BaseConfig) *botBackend {
+// NewBot returns a bot paying out from wallets, at least one wallet is required.
+func NewBot(backend Client, wallets []*Wallet, cfg BaseConfig) *botBackend {
 	b := &botBackend{
-		backend:  backend,
-		key:      key,
-		public:   publicKey,
-		backoff:  make(map[string]time.Time),
-		handlers: make(map[string]func(cmd []string) (string, error)),
-		cfg:      cfg,
-	}
-	b.handlers = map[string]func(cmd []string) (string, error){
+		backend: backend,
+		wallets: wallets,
+		backoff: make(map[string]time.Time),
+		payouts: make(map[string][]payout),
+		cfg:     cfg,
+		now:     time.Now,
+	}
+	b.handlers = map[string]handlerFunc{
 		balance:      b.getBalance,
 		help:         b.getHelp,
 		faucetStatus: b.getFaucetStatus,
 		faucetAddr:   b.getFaucetAddress,
 		txInfo:       b.getTxInfo,
-		dumpTxs:      b.getDumpTx}
+		dumpTxs:      b.getDumpTx,
+		myTier:       b.getMyTier,
+		recentPays:   b.getRecentPayouts}
 
 	return b
 }
@@ -93,83 +174,88 @@ const (
 	faucetStatus = "$faucet_status"
 	faucetAddr   = "$faucet_addr"
 	txInfo       = "$tx_info"
+	myTier       = "$my_tier"
+	recentPays   = "$recent_payouts"
 )
 
-func (b *botBackend) OnMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
-	// Ignore all messages created by the bot itself
-	// This isn't required in this specific example but it's a good practice.
-	if m.Author.ID == s.State.User.ID {
-		return
+// Handle dispatches a request to its command handler. Messages starting with
+// an address are fund requests, anything else returns ErrUnknownCommand.
+func (b *botBackend) Handle(req *Request) (*Response, error) {
+	if len(req.Args) == 0 {
+		return nil, ErrUnknownCommand
 	}
-
-	spllited := strings.Split(m.Content, " ")
-	println("got new message ", m.Content)
-
-	if handler, has := b.handlers[spllited[0]]; has {
-		out, err := handler(spllited)
-		if err != nil {
-			println(err.Error())
-			return
-		}
-		_, err = s.ChannelMessageSend(m.ChannelID, out)
-		if err != nil {
-			println(err.Error())
-		}
-	} else {
-		if strings.HasPrefix(strings.ToLower(spllited[0]), "0x") {
-			out, err := b.transferFunds(spllited)
-			if err != nil {
-				println(err.Error())
-				_, _ = s.ChannelMessageSend(m.ChannelID, err.Error())
-				return
-			}
-			_, err = s.ChannelMessageSend(m.ChannelID, out)
-			if err != nil {
-				println(err.Error())
-			}
-
-		}
+	if handler, has := b.handlers[req.Args[0]]; has {
+		return handler(req)
 	}
-
+	if strings.HasPrefix(strings.ToLower(req.Args[0]), "0x") {
+		return b.transferFunds(req)
+	}
+	return nil, ErrUnknownCommand
 }
 
-func (b *botBackend) getBalance(cmd []string) (string, error) {
+func (b *botBackend) getBalance(req *Request) (*Response, error) {
+	cmd := req.Args
 	if len(cmd) < 2 {
-		return "", fmt.Errorf("account name not provided")
+		return nil, fmt.Errorf("account name not provided")
 	}
 	address := gosmtypes.BytesToAddress(util.FromHex(cmd[1]))
 	if address.Big().Uint64() == 0 {
-		return "", fmt.Errorf("wrong address format")
+		return nil, fmt.Errorf("wrong address format")
 	}
-	state, err := b.backend.AccountState(address)
+	state, err := b.node(req).AccountState(address)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return fmt.Sprintf("account %v balance %v", address.String(), state.GetStateCurrent().Balance.Value), nil
+	res := &BalanceResult{Address: address.String(), Balance: state.GetStateCurrent().Balance.Value}
+	return &Response{
+		Command: balance,
+		Text:    fmt.Sprintf("account %v balance %v", res.Address, res.Balance),
+		Data:    res,
+	}, nil
 }
 
-func (b *botBackend) getHelp(cmd []string) (string, error) {
-	return helpText, nil
+func (b *botBackend) getHelp(req *Request) (*Response, error) {
+	text, ok := helpTexts[req.Guild.language()]
+	if !ok {
+		text = helpText
+	}
+	return &Response{Command: help, Text: text}, nil
 }
 
-func (b *botBackend) getDumpTx(cmd []string) (string, error) {
+func (b *botBackend) getDumpTx(req *Request) (*Response, error) {
+	cmd := req.Args
 	if len(cmd) < 2 {
-		return "", fmt.Errorf("account name not provided")
+		return nil, fmt.Errorf("account name not provided")
 	}
 	address := gosmtypes.BytesToAddress(util.FromHex(cmd[1]))
 	if address.Big().Uint64() == 0 {
-		return "", fmt.Errorf("wrong address format")
+		return nil, fmt.Errorf("wrong address format")
 	}
-	txs, _, err := b.backend.GetMeshTransactions(address, 0, 100)
+	txs, _, err := b.node(req).GetMeshTransactions(address, 0, 100)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	res := &TxsResult{Address: address.String(), Txs: make([]TxResult, 0, len(txs))}
 	str := ""
 	for _, tx := range txs {
+		res.Txs = append(res.Txs, meshTxResult(tx))
 		str += getTxStr(tx)
 	}
-	return str, nil
+	return &Response{Command: dumpTxs, Text: str, Data: res}, nil
+}
+
+func meshTxResult(tranasction *apitypes.MeshTransaction) TxResult {
+	tx := tranasction.Transaction
+	ct := tx.GetCoinTransfer()
+	return TxResult{
+		ID:     "0x" + Bytes2Hex(tx.GetId().GetId()),
+		From:   gosmtypes.BytesToAddress(tx.Sender.Address).String(),
+		To:     gosmtypes.BytesToAddress(ct.Receiver.Address).String(),
+		Amount: tx.Amount.GetValue(),
+		Fee:    tx.GasOffered.GetGasPrice(),
+		Layer:  tranasction.LayerId.Number,
+	}
 }
 
 func getTxStr(tranasction *apitypes.MeshTransaction) string {
@@ -184,113 +270,270 @@ func getTxStr(tranasction *apitypes.MeshTransaction) string {
 	return msg
 }
 
-func (b *botBackend) getFaucetStatus(cmd []string) (string, error) {
-	address := b.public
-	if address.Big().Uint64() == 0 {
-		return "", fmt.Errorf("wrong address format")
+func (b *botBackend) getFaucetStatus(req *Request) (*Response, error) {
+	if b.isDegraded() {
+		res := &FaucetStatusResult{Address: b.wallets[0].Address.String(), Degraded: true}
+		b.mu.Lock()
+		res.Confirmed, res.Failed = b.confirmed, b.failed
+		b.mu.Unlock()
+		return &Response{Command: faucetStatus, Text: "⚠️ The faucet lost its connection to the node and is reconnecting, payouts are paused", Data: res}, nil
 	}
-	state, err := b.backend.AccountState(address)
+	node := b.node(req)
+	status, err := node.NodeStatus()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	status, err := b.backend.NodeStatus()
-	if err != nil {
-		return "", err
+	res := &FaucetStatusResult{
+		Address:  b.wallets[0].Address.String(),
+		Synced:   status.IsSynced,
+		Peers:    status.ConnectedPeers,
+		TopLayer: status.TopLayer.GetNumber(),
 	}
-
-	return fmt.Sprintf("Balance: %v\n Synced: %v\n Peers: %v\n Layer :%v", state.StateProjected.Balance, status.IsSynced, status.ConnectedPeers, status.TopLayer), nil
-}
-
-func (b *botBackend) getFundAmount() uint64 {
-	return b.cfg.TransferAmount
-}
-
-func (b *botBackend) getFaucetAddr() gosmtypes.Address {
-	return b.public
-}
-
-func (b *botBackend) getFaucetAddress(cmd []string) (string, error) {
-	return b.public.String(), nil
+	walletsText := ""
+	for _, w := range b.wallets {
+		st, err := b.walletStatus(node, w)
+		if err != nil {
+			return nil, err
+		}
+		res.Balance += st.Balance
+		res.Wallets = append(res.Wallets, *st)
+		walletsText += fmt.Sprintf("\n %v balance: %v pending: %v", st.Address, st.Balance, st.Pending)
+	}
+	b.mu.Lock()
+	res.Confirmed, res.Failed = b.confirmed, b.failed
+	b.mu.Unlock()
+	text := fmt.Sprintf("Balance: %v\n Synced: %v\n Peers: %v\n Layer :%v", res.Balance, status.IsSynced, status.ConnectedPeers, status.TopLayer)
+	if b.tracker != nil {
+		text += fmt.Sprintf("\n Payouts confirmed: %v failed: %v", res.Confirmed, res.Failed)
+	}
+	if len(b.wallets) > 1 {
+		text += "\n Wallets:" + walletsText
+	}
+	return &Response{Command: faucetStatus, Text: text, Data: res}, nil
 }
 
-func (b *botBackend) getFaucetPrivateKey() ed25519.PrivateKey {
-	return b.key
+func (b *botBackend) getFaucetAddress(req *Request) (*Response, error) {
+	res := &FaucetAddressResult{Address: b.wallets[0].Address.String()}
+	for _, w := range b.wallets {
+		res.Addresses = append(res.Addresses, w.Address.String())
+	}
+	return &Response{
+		Command: faucetAddr,
+		Text:    strings.Join(res.Addresses, "\n"),
+		Data:    res,
+	}, nil
 }
 
-func (b *botBackend) getTxInfo(cmd []string) (string, error) {
-	addr := cmd[1]
+func (b *botBackend) getTxInfo(req *Request) (*Response, error) {
+	if len(req.Args) < 2 {
+		return nil, fmt.Errorf("transaction id not provided")
+	}
+	addr := req.Args[1]
 	bts := util.FromHex(addr)
-	state, tx, err := b.backend.TransactionState(bts, true)
+	state, tx, err := b.node(req).TransactionState(bts, true)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	// the node answers an unknown id with an empty tx
 	ct := tx.GetCoinTransfer()
-	msg := fmt.Sprintf("tx info: from: %v\nto %v\namount %v\nfee %v\nstatus %v", gosmtypes.BytesToAddress(tx.Sender.Address).String(), gosmtypes.BytesToAddress(ct.Receiver.Address).String(), tx.Amount, tx.GasOffered, state.State.String())
-	return msg, nil
+	if tx.GetSender() == nil || ct == nil || state.GetState() == apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	res := &TxResult{
+		ID:     "0x" + Bytes2Hex(bts),
+		From:   gosmtypes.BytesToAddress(tx.GetSender().GetAddress()).String(),
+		To:     gosmtypes.BytesToAddress(ct.GetReceiver().GetAddress()).String(),
+		Amount: tx.GetAmount().GetValue(),
+		Fee:    tx.GetGasOffered().GetGasPrice(),
+		State:  state.GetState().String(),
+	}
+	msg := fmt.Sprintf("tx info: from: %v\nto %v\namount %v\nfee %v\nstatus %v", res.From, res.To, tx.Amount, tx.GasOffered, res.State)
+	return &Response{Command: txInfo, Text: msg, Data: res}, nil
 }
 
-func (b *botBackend) transferFunds(cmd []string) (string, error) {
-	if err := b.canSubmitTransactions(); err != nil {
-		return "", err
+func (b *botBackend) transferFunds(req *Request) (resp *Response, err error) {
+	cmd := req.Args
+	// tracking starts after the payout is audited, so its final state is recorded after it
+	var track func()
+	defer func() {
+		if track != nil {
+			track()
+		}
+	}()
+	// the request is served with one config even if it is reloaded meanwhile
+	cfg := b.config().withGuild(req.Guild)
+	entry := &AuditEntry{Network: cfg.Network, Requester: req.RequesterID, RequesterName: req.RequesterName, Address: cmd[0], Simulated: cfg.DryRun}
+	submitted := false
+	defer func() { b.audit(entry, submitted, err) }()
+
+	log := req.Logger()
+	node := b.node(req)
+	if err := b.canSubmitTransactions(node); err != nil {
+		return nil, err
 	}
 
 	destAddressStr := cmd[0]
 	destAddress, err := gosmtypes.StringToAddress(destAddressStr)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	// short addresses would be zero padded to a valid but unintended address
+	if len(FromHex(destAddressStr)) != gosmtypes.AddressLength {
+		return nil, fmt.Errorf("wrong address format")
 	}
 
-	amount := uint64(b.cfg.TransferAmount) //todo: default amount
-	gas := uint64(50)
+	tier := cfg.resolveTier(req.Roles)
+	amount := tier.TransferAmount
+	gas := cfg.fee()
+	entry.Amount = amount
+	entry.Reason = "tier " + tier.Name
 
-	account, err := b.backend.AccountState(b.getFaucetAddr())
+	now := b.now()
+	cancel, err := b.reserveRequest(req, destAddress, tier, now)
 	if err != nil {
-		println("err reading faucet status")
+		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
 
-	fmt.Println("New transaction summary:")
-	fmt.Println("To:    ", destAddress.String())
-	fmt.Println("Nonce: ", account.StateProjected.Counter)
+	wallet, release, err := b.pickWallet(node, amount+gas)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	entry.Wallet = wallet.Address.String()
 
-	state, err := b.backend.AccountState(b.getFaucetAddr())
+	account, err := node.AccountState(wallet.Address)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if state.StateProjected.Balance.Value < b.getFundAmount()+gas {
-		return "", fmt.Errorf("insufficient funds")
+	log.Info("new transaction",
+		zap.String("from", wallet.Address.String()),
+		zap.String("to", destAddress.String()),
+		zap.Uint64("nonce", account.StateProjected.Counter),
+		zap.Uint64("amount", amount))
+
+	if account.StateProjected.Balance.Value < amount+gas {
+		return nil, fmt.Errorf("insufficient funds")
 	}
 
-	if ts, ok := b.backoff[destAddress.String()]; ok {
-		if time.Now().Before(ts) {
-			return "", fmt.Errorf("account %v requested funds too soon", destAddress.String())
+	entry.Nonce = account.StateProjected.Counter
+	if cfg.DryRun {
+		// the request passed every check, tell what would have been sent
+		res := &TransferResult{
+			Address: destAddress.String(),
+			Amount:  amount,
+			Tier:    tier.Name,
+			Time:    now,
+			DryRun:  true,
 		}
+		return &Response{
+			Command: CommandTransfer,
+			Text: fmt.Sprintf("🧪 dry run: would transfer %v to %v (tier: %v) from %v with nonce %v",
+				amount, res.Address, tier.Name, wallet.Address.String(), entry.Nonce),
+			Data: res,
+		}, nil
 	}
 
-	txState, err := b.backend.Transfer(destAddress, account.StateProjected.Counter, amount, gas, 100, b.getFaucetPrivateKey())
+	submitted = true
+	txState, err := node.Transfer(destAddress, account.StateProjected.Counter, amount, gas, 100, wallet.Key)
 	if err != nil {
-		return "", fmt.Errorf("🚫 tx rejected by node, %v", err)
+		return nil, fmt.Errorf("🚫 tx rejected by node, %v", err)
 	}
 
 	txStateDispString := transactionStateDisStringsMap[int32(txState.State.Number())]
-	fmt.Println("Transaction submitted.")
-	fmt.Println(fmt.Sprintf("Transaction id: 0x%v", hex.EncodeToString(txState.Id.Id)))
-	fmt.Println("Transaction state:", txStateDispString)
+	entry.TxID = "0x" + Bytes2Hex(txState.Id.Id)
+	entry.State = txStateDispString
+	log.Info("transaction submitted", zap.String("tx_id", entry.TxID), zap.String("state", txStateDispString))
 
 	if txState.State <= apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING {
-		return "", fmt.Errorf("🚫 tx rejected by node, %v", txStateDispString)
+		return nil, fmt.Errorf("🚫 tx rejected by node, %v", txStateDispString)
+	}
+
+	res := &TransferResult{
+		Address: destAddress.String(),
+		Amount:  amount,
+		Tier:    tier.Name,
+		TxID:    entry.TxID,
+		State:   txStateDispString,
+		Time:    now,
+	}
+	b.mu.Lock()
+	b.addRecentPayout(*res)
+	b.mu.Unlock()
+	track = func() { b.tracker.Track(txState.Id.Id, txState.State, req, *res) }
+	return &Response{
+		Command: CommandTransfer,
+		Text:    fmt.Sprintf("💸  transferred %v to %v (tier: %v)\n txID: %v", amount, res.Address, tier.Name, res.TxID),
+		Data:    res,
+	}, nil
+}
+
+// reserveRequest checks the cooldowns and daily cap of a fund request and
+// starts the cooldowns right away, so concurrent requests of the same requester
+// or address can not both pass. The returned cancel undoes the reservation.
+func (b *botBackend) reserveRequest(req *Request, dest gosmtypes.Address, tier TierConfig, now time.Time) (cancel func(), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	addrKey, userKey := dest.String(), userBackoffKey(req.RequesterID)
+	if ts, ok := b.backoff[addrKey]; ok {
+		if now.Before(ts) {
+			return nil, &CoolDownError{Msg: fmt.Sprintf("account %v requested funds too soon", dest.String()), Retry: ts.Sub(now)}
+		}
+	}
+	if ts, ok := b.backoff[userKey]; ok {
+		if now.Before(ts) {
+			return nil, &CoolDownError{Msg: fmt.Sprintf("%v you can request funds again in %v", req.RequesterName, ts.Sub(now).Round(time.Second)), Retry: ts.Sub(now)}
+		}
+	}
+	if tier.DailyCap > 0 && b.dailyTotal(req.RequesterID, now)+tier.TransferAmount > tier.DailyCap {
+		return nil, &CoolDownError{Msg: fmt.Sprintf("%v daily cap of %v reached for tier %v", req.RequesterName, tier.DailyCap, tier.Name), Retry: dailyCapWindow}
 	}
 
-	b.backoff[destAddress.String()] = time.Now().Add(b.cfg.RequestCoolDown)
+	prevAddr, hadAddr := b.backoff[addrKey]
+	prevUser, hadUser := b.backoff[userKey]
+	b.backoff[addrKey] = now.Add(tier.RequestCoolDown)
+	b.backoff[userKey] = now.Add(tier.RequestCoolDown)
+	b.payouts[req.RequesterID] = append(b.payouts[req.RequesterID], payout{at: now, amount: tier.TransferAmount})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		restoreBackoff(b.backoff, addrKey, prevAddr, hadAddr)
+		restoreBackoff(b.backoff, userKey, prevUser, hadUser)
+		payouts := b.payouts[req.RequesterID]
+		for i := len(payouts) - 1; i >= 0; i-- {
+			if payouts[i].at.Equal(now) && payouts[i].amount == tier.TransferAmount {
+				b.payouts[req.RequesterID] = append(payouts[:i:i], payouts[i+1:]...)
+				break
+			}
+		}
+	}, nil
+}
 
-	return fmt.Sprintf("💸  transferred funds to %v\n txID: %v", destAddress.String(), "0x"+Bytes2Hex(txState.Id.Id)), nil
+func restoreBackoff(backoff map[string]time.Time, key string, prev time.Time, had bool) {
+	if had {
+		backoff[key] = prev
+	} else {
+		delete(backoff, key)
+	}
 }
 
 // canSubmitTransactions returns true if the node is accepting transactions.
 // todo: this should move to a method in the transactions service.
-func (b *botBackend) canSubmitTransactions() error {
-	status, err := b.backend.NodeStatus()
+func (b *botBackend) canSubmitTransactions(node Client) error {
+	if b.isDegraded() {
+		return ErrDegraded
+	}
+	status, err := node.NodeStatus()
 	if err != nil {
 		return fmt.Errorf("node not available %v", err)
 	}
@@ -303,6 +546,19 @@ func (b *botBackend) canSubmitTransactions() error {
 	return nil
 }
 
+// ConnStateChanged pauses payouts while the connection to the node is degraded.
+func (b *botBackend) ConnStateChanged(network string, state ConnState) {
+	var degraded int32
+	if state == ConnDegraded {
+		degraded = 1
+	}
+	atomic.StoreInt32(&b.degraded, degraded)
+}
+
+func (b *botBackend) isDegraded() bool {
+	return atomic.LoadInt32(&b.degraded) == 1
+}
+
 func interfaceToBytes(i interface{}) ([]byte, error) {
 	var w bytes.Buffer
 	if _, err := xdr.Marshal(&w, &i); err != nil {
@@ -0,0 +1,550 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAddress      = "0x1234567890123456789012345678901234567890"
+	otherTestAddress = "0x0987654321098765432109876543210987654321"
+)
+
+func testWallet(seed byte) *Wallet {
+	buf := make([]byte, ed25519.PrivateKeySize)
+	buf[0] = seed
+	key, err := NewPrivateKeyFromBuffer(buf)
+	if err != nil {
+		panic(err)
+	}
+	return NewWallet(key)
+}
+
+func testConfig() BaseConfig {
+	return BaseConfig{
+		Network:         "testnet",
+		TransferAmount:  100,
+		RequestCoolDown: time.Hour,
+	}
+}
+
+// newTestBot returns a bot paying from wallets, each funded with 10000.
+func newTestBot(t *testing.T, cfg BaseConfig, wallets ...*Wallet) (*botBackend, *fakeClient, *fakeClock) {
+	t.Helper()
+	if len(wallets) == 0 {
+		wallets = []*Wallet{testWallet(1)}
+	}
+	client := newFakeClient()
+	for _, w := range wallets {
+		client.setAccount(w.Address, 10000, 0, 10000, 0)
+	}
+	clock := newFakeClock()
+	b := NewBot(client, wallets, cfg)
+	b.now = clock.now
+	return b, client, clock
+}
+
+func transferRequest(requester, address string, roles ...string) *Request {
+	return &Request{
+		Frontend:      "test",
+		RequesterID:   requester,
+		RequesterName: requester,
+		Roles:         roles,
+		Args:          []string{address},
+	}
+}
+
+func command(args ...string) *Request {
+	return &Request{Frontend: "test", RequesterID: "alice", RequesterName: "alice", Args: args}
+}
+
+func TestHandleUnknownCommand(t *testing.T) {
+	b, _, _ := newTestBot(t, testConfig())
+	for _, args := range [][]string{nil, {"$nope"}, {"hello", "there"}} {
+		_, err := b.Handle(command(args...))
+		if err != ErrUnknownCommand {
+			t.Errorf("Handle(%q) error = %v, want ErrUnknownCommand", args, err)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	b, _, _ := newTestBot(t, testConfig())
+	resp, err := b.Handle(command(help))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Command != help || resp.Text != helpText {
+		t.Errorf("unexpected help response %+v", resp)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+	addr, _ := gosmtypes.StringToAddress(testAddress)
+	client.setAccount(addr, 500, 1, 400, 2)
+
+	if _, err := b.Handle(command(balance)); err == nil {
+		t.Error("expected an error without an address")
+	}
+	if _, err := b.Handle(command(balance, "0x0")); err == nil {
+		t.Error("expected an error for the zero address")
+	}
+
+	resp, err := b.Handle(command(balance, testAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*BalanceResult)
+	// the balance is the current balance, not the projected one
+	if res.Balance != 500 || res.Address != addr.String() {
+		t.Errorf("unexpected balance result %+v", res)
+	}
+}
+
+func TestGetDumpTxs(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+	from, to := testWallet(1).Address, testWallet(2).Address
+	client.meshTxs = []*apitypes.MeshTransaction{{
+		Transaction: &apitypes.Transaction{
+			Id:         &apitypes.TransactionId{Id: []byte{0xab}},
+			Datum:      &apitypes.Transaction_CoinTransfer{CoinTransfer: &apitypes.CoinTransferTransaction{Receiver: &apitypes.AccountId{Address: to.Bytes()}}},
+			Sender:     &apitypes.AccountId{Address: from.Bytes()},
+			GasOffered: &apitypes.GasOffered{GasPrice: 50},
+			Amount:     &apitypes.Amount{Value: 300},
+		},
+		LayerId: &apitypes.LayerNumber{Number: 7},
+	}}
+
+	if _, err := b.Handle(command(dumpTxs)); err == nil {
+		t.Error("expected an error without an address")
+	}
+
+	resp, err := b.Handle(command(dumpTxs, to.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*TxsResult)
+	want := TxResult{ID: "0xab", From: from.String(), To: to.String(), Amount: 300, Fee: 50, Layer: 7}
+	if len(res.Txs) != 1 || res.Txs[0] != want {
+		t.Errorf("txs = %+v, want [%+v]", res.Txs, want)
+	}
+}
+
+func TestGetFaucetStatus(t *testing.T) {
+	w1, w2 := testWallet(1), testWallet(2)
+	b, client, _ := newTestBot(t, testConfig(), w1, w2)
+	client.setAccount(w1.Address, 1200, 3, 1000, 5)
+	client.setAccount(w2.Address, 500, 0, 500, 0)
+
+	resp, err := b.Handle(command(faucetStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*FaucetStatusResult)
+	if res.Balance != 1500 || !res.Synced || res.Peers != 5 || res.TopLayer != 10 {
+		t.Errorf("unexpected status %+v", res)
+	}
+	if len(res.Wallets) != 2 || res.Wallets[0].Pending != 2 || res.Wallets[1].Pending != 0 {
+		t.Errorf("unexpected wallets %+v", res.Wallets)
+	}
+
+	client.statusErr = errors.New("connection refused")
+	if _, err := b.Handle(command(faucetStatus)); err == nil {
+		t.Error("expected the node error")
+	}
+}
+
+func TestGetFaucetAddress(t *testing.T) {
+	w1, w2 := testWallet(1), testWallet(2)
+	b, _, _ := newTestBot(t, testConfig(), w1, w2)
+	resp, err := b.Handle(command(faucetAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*FaucetAddressResult)
+	if res.Address != w1.Address.String() || len(res.Addresses) != 2 || res.Addresses[1] != w2.Address.String() {
+		t.Errorf("unexpected addresses %+v", res)
+	}
+}
+
+func TestGetTxInfo(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+	from, to := testWallet(1).Address, testWallet(2).Address
+	client.txs[string([]byte{0x01, 0x02})] = &apitypes.Transaction{
+		Id:         &apitypes.TransactionId{Id: []byte{0x01, 0x02}},
+		Datum:      &apitypes.Transaction_CoinTransfer{CoinTransfer: &apitypes.CoinTransferTransaction{Receiver: &apitypes.AccountId{Address: to.Bytes()}}},
+		Sender:     &apitypes.AccountId{Address: from.Bytes()},
+		GasOffered: &apitypes.GasOffered{GasPrice: 50},
+		Amount:     &apitypes.Amount{Value: 300},
+	}
+
+	if _, err := b.Handle(command(txInfo)); err == nil {
+		t.Error("expected an error without a tx id")
+	}
+	if _, err := b.Handle(command(txInfo, "0x0304")); err == nil {
+		t.Error("expected an error for an unknown tx")
+	}
+	// the node answers an unknown id with an empty tx in an unspecified state
+	client.states[string([]byte{0xde, 0xad})] = apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+	if _, err := b.Handle(command(txInfo, "0xdead")); err == nil || err.Error() != "transaction not found" {
+		t.Errorf("empty tx error = %v, want transaction not found", err)
+	}
+	client.txs[string([]byte{0x05})] = &apitypes.Transaction{Sender: &apitypes.AccountId{Address: from.Bytes()}}
+	if _, err := b.Handle(command(txInfo, "0x05")); err == nil {
+		t.Error("expected an error for a tx without a coin transfer")
+	}
+
+	resp, err := b.Handle(command(txInfo, "0x0102"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*TxResult)
+	if res.ID != "0x0102" || res.From != from.String() || res.To != to.String() || res.Amount != 300 || res.Fee != 50 ||
+		res.State != apitypes.TransactionState_TRANSACTION_STATE_PROCESSED.String() {
+		t.Errorf("unexpected tx info %+v", res)
+	}
+}
+
+func TestGetMyTier(t *testing.T) {
+	cfg := testConfig()
+	cfg.DailyCap = 1000
+	cfg.Tiers = []TierConfig{{Name: "core", Roles: []string{"core-role"}, TransferAmount: 500, RequestCoolDown: time.Minute, DailyCap: 5000}}
+	b, _, clock := newTestBot(t, cfg)
+
+	req := command(myTier)
+	resp, err := b.Handle(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Data.(*TierResult)
+	if res.Tier != defaultTierName || res.Amount != 100 || res.DailyCap != 1000 || !res.NextRequest.IsZero() {
+		t.Errorf("unexpected default tier %+v", res)
+	}
+
+	if _, err := b.Handle(transferRequest("alice", testAddress, "core-role")); err != nil {
+		t.Fatal(err)
+	}
+	req.Roles = []string{"other-role", "core-role"}
+	resp, err = b.Handle(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = resp.Data.(*TierResult)
+	if res.Tier != "core" || res.Amount != 500 || res.DailyUsed != 500 {
+		t.Errorf("unexpected core tier %+v", res)
+	}
+	if want := clock.now().Add(time.Minute); !res.NextRequest.Equal(want) {
+		t.Errorf("next request = %v, want %v", res.NextRequest, want)
+	}
+}
+
+func TestGetRecentPayouts(t *testing.T) {
+	b, _, clock := newTestBot(t, testConfig())
+	resp, err := b.Handle(command(recentPays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(resp.Data.(*RecentPayoutsResult).Payouts); n != 0 {
+		t.Fatalf("got %v payouts before any transfer", n)
+	}
+
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(time.Second)
+	if _, err := b.Handle(transferRequest("bob", otherTestAddress)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = b.Handle(command(recentPays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payouts := resp.Data.(*RecentPayoutsResult).Payouts
+	if len(payouts) != 2 || !strings.EqualFold(payouts[0].Address, otherTestAddress) || !strings.EqualFold(payouts[1].Address, testAddress) {
+		t.Errorf("payouts not newest first: %+v", payouts)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	w := testWallet(1)
+	b, client, clock := newTestBot(t, testConfig(), w)
+	client.setAccount(w.Address, 10000, 7, 10000, 7)
+
+	resp, err := b.Handle(transferRequest("alice", testAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := client.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %v transfers, want 1", len(sent))
+	}
+	to, _ := gosmtypes.StringToAddress(testAddress)
+	want := fakeTransfer{from: w.Address, to: to, nonce: 7, amount: 100, gasPrice: 50, gasLimit: 100, projected: 7}
+	if sent[0] != want {
+		t.Errorf("transfer = %+v, want %+v", sent[0], want)
+	}
+
+	res := resp.Data.(*TransferResult)
+	if resp.Command != CommandTransfer || res.Amount != 100 || res.Tier != defaultTierName || res.TxID != "0x01" || !res.Time.Equal(clock.now()) {
+		t.Errorf("unexpected transfer result %+v", res)
+	}
+}
+
+func TestTransferCoolDown(t *testing.T) {
+	b, client, clock := newTestBot(t, testConfig())
+
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+
+	var cdErr *CoolDownError
+	// the same requester to another address
+	_, err := b.Handle(transferRequest("alice", otherTestAddress))
+	if !errors.As(err, &cdErr) || cdErr.Retry != time.Hour {
+		t.Errorf("error = %v, want a cooldown of 1h", err)
+	}
+	// another requester to the same address
+	clock.advance(30 * time.Minute)
+	_, err = b.Handle(transferRequest("bob", testAddress))
+	if !errors.As(err, &cdErr) || cdErr.Retry != 30*time.Minute {
+		t.Errorf("error = %v, want a cooldown of 30m", err)
+	}
+	if n := len(client.sent()); n != 1 {
+		t.Fatalf("sent %v transfers during the cooldown", n)
+	}
+
+	clock.advance(30 * time.Minute)
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Errorf("request after the cooldown failed: %v", err)
+	}
+}
+
+func TestTransferDailyCap(t *testing.T) {
+	cfg := testConfig()
+	cfg.RequestCoolDown = time.Minute
+	cfg.DailyCap = 250
+	b, client, clock := newTestBot(t, cfg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+			t.Fatal(err)
+		}
+		clock.advance(time.Hour)
+	}
+
+	var cdErr *CoolDownError
+	if _, err := b.Handle(transferRequest("alice", testAddress)); !errors.As(err, &cdErr) {
+		t.Errorf("error = %v, want the daily cap", err)
+	}
+	if n := len(client.sent()); n != 2 {
+		t.Errorf("sent %v transfers, want 2", n)
+	}
+
+	clock.advance(dailyCapWindow)
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Errorf("request after the daily cap window failed: %v", err)
+	}
+}
+
+func TestTransferInsufficientFunds(t *testing.T) {
+	w := testWallet(1)
+	b, client, _ := newTestBot(t, testConfig(), w)
+	// the transfer amount is covered but not the gas
+	client.setAccount(w.Address, 120, 0, 120, 0)
+
+	_, err := b.Handle(transferRequest("alice", testAddress))
+	if err == nil || !strings.Contains(err.Error(), "insufficient funds") {
+		t.Fatalf("error = %v, want insufficient funds", err)
+	}
+	if n := len(client.sent()); n != 0 {
+		t.Fatalf("sent %v transfers without funds", n)
+	}
+
+	// a failed request does not start the cooldown
+	client.setAccount(w.Address, 10000, 0, 10000, 0)
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Errorf("request after refunding failed: %v", err)
+	}
+}
+
+func TestTransferPicksWallet(t *testing.T) {
+	w1, w2, w3 := testWallet(1), testWallet(2), testWallet(3)
+	b, client, _ := newTestBot(t, testConfig(), w1, w2, w3)
+	client.setAccount(w1.Address, 5000, 0, 5000, 3)
+	client.setAccount(w2.Address, 2000, 0, 2000, 0)
+	client.setAccount(w3.Address, 100, 0, 100, 0)
+
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	// w3 can not pay, w2 has no pending transactions
+	if from := client.sent()[0].from; from != w2.Address {
+		t.Errorf("paid from %v, want %v", from.String(), w2.Address.String())
+	}
+}
+
+func TestTransferNodeNotReady(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+
+	client.status.IsSynced = false
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err == nil || !strings.Contains(err.Error(), "not synced") {
+		t.Errorf("error = %v, want node not synced", err)
+	}
+
+	client.status.IsSynced = true
+	client.statusErr = errors.New("connection refused")
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("error = %v, want node not available", err)
+	}
+
+	if n := len(client.sent()); n != 0 {
+		t.Errorf("sent %v transfers to a node not ready", n)
+	}
+}
+
+func TestTransferRejected(t *testing.T) {
+	for _, state := range []apitypes.TransactionState_TransactionState{
+		apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED,
+		apitypes.TransactionState_TRANSACTION_STATE_REJECTED,
+		apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+		apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING,
+	} {
+		t.Run(state.String(), func(t *testing.T) {
+			b, client, _ := newTestBot(t, testConfig())
+			client.txState = state
+
+			_, err := b.Handle(transferRequest("alice", testAddress))
+			if err == nil || !strings.Contains(err.Error(), "rejected") {
+				t.Fatalf("error = %v, want rejected", err)
+			}
+
+			// the cooldown is released so the requester can retry right away
+			client.txState = apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL
+			if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+				t.Errorf("retry failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestTransferNodeError(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+	client.transferErr = errors.New("deadline exceeded")
+
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Fatalf("error = %v, want the node error", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.backoff) != 0 || len(b.recent) != 0 {
+		t.Errorf("failed transfer left backoff %v and recent payouts %v", b.backoff, b.recent)
+	}
+}
+
+func TestTransferMalformedAddress(t *testing.T) {
+	for _, addr := range []string{
+		"0xzz34567890123456789012345678901234567890",
+		"0x1234",
+		"0x",
+		testAddress + "12",
+	} {
+		b, client, _ := newTestBot(t, testConfig())
+		if _, err := b.Handle(transferRequest("alice", addr)); err == nil {
+			t.Errorf("no error for address %q", addr)
+		}
+		if n := len(client.sent()); n != 0 {
+			t.Errorf("sent %v transfers to address %q", n, addr)
+		}
+		if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+			t.Errorf("malformed address %q started the cooldown: %v", addr, err)
+		}
+	}
+}
+
+func TestTransferDryRun(t *testing.T) {
+	cfg := testConfig()
+	cfg.DryRun = true
+	w := testWallet(1)
+	b, client, _ := newTestBot(t, cfg, w)
+	client.setAccount(w.Address, 10000, 7, 10000, 7)
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := OpenAuditLog(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	b.SetAuditLog(auditLog)
+
+	resp, err := b.Handle(transferRequest("alice", testAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(client.sent()); n != 0 {
+		t.Errorf("sent %v transfers in a dry run", n)
+	}
+	if res := resp.Data.(*TransferResult); !res.DryRun || res.Amount != 100 || res.TxID != "" {
+		t.Errorf("unexpected dry run result %+v", res)
+	}
+	if !strings.Contains(resp.Text, "nonce 7") {
+		t.Errorf("reply %q does not tell the nonce", resp.Text)
+	}
+	// the cooldown applies as if the transfer was sent
+	var cdErr *CoolDownError
+	if _, err := b.Handle(transferRequest("alice", testAddress)); !errors.As(err, &cdErr) {
+		t.Errorf("error = %v, want a cooldown", err)
+	}
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	last, err := VerifyAuditLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 2 || last.Decision != AuditDenied || !last.Simulated {
+		t.Errorf("unexpected last audit entry %+v", last)
+	}
+}
+
+func TestTransferConcurrentNonces(t *testing.T) {
+	w := testWallet(1)
+	b, client, _ := newTestBot(t, testConfig(), w)
+
+	const requests = 10
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func(i int) {
+			addr := fmt.Sprintf("0x%040x", i+1)
+			_, err := b.Handle(transferRequest(fmt.Sprint("user", i), addr))
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < requests; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	nonces := make(map[uint64]bool)
+	for _, s := range client.sent() {
+		if nonces[s.nonce] {
+			t.Errorf("nonce %v used twice", s.nonce)
+		}
+		nonces[s.nonce] = true
+	}
+	if len(nonces) != requests {
+		t.Errorf("sent %v distinct nonces, want %v", len(nonces), requests)
+	}
+}
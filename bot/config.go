@@ -2,36 +2,152 @@ package bot
 
 import (
 	"fmt"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
+// BaseConfig holds the bot configuration.
+// Secret fields (token, telegram-token, priv-key, mnemonic and their reserve-
+// and network counterparts) may be given as "env:VAR_NAME" or
+// "file:/path" references which are resolved when the config is loaded.
 type BaseConfig struct {
-	Mnemonic       string `mapstructure:"mnemonic"`
+	// Network names the network of the top level config
+	Network string `mapstructure:"network"`
+	// NetworkChannels are the channel IDs bound to the network, set for configured networks
+	NetworkChannels []string        `mapstructure:"-"`
+	Networks        []NetworkConfig `mapstructure:"networks"`
+
+	Mnemonic string `mapstructure:"mnemonic"`
+	// WalletCount is the number of hot wallets derived from the mnemonic
+	WalletCount    int    `mapstructure:"wallet-count"`
 	PublicKey      string `mapstructure:"pub-key"`
 	PrivateKey     string `mapstructure:"priv-key"`
-	TransferAmount uint64   `mapstructure:"transfer-amount"`
+	TransferAmount uint64 `mapstructure:"transfer-amount"`
+	Fee            uint64 `mapstructure:"fee"`
 	Server         string `mapstructure:"server"`
-	BotToken string `mapstructure:"token"`
-	RequestCoolDown time.Duration `mapstructure:"cooldown"`
-	SecureConnection bool `mapstructure:"secure"`
+	// Servers configures a pool of nodes used instead of Server
+	Servers           []string      `mapstructure:"servers"`
+	NodeCheckInterval time.Duration `mapstructure:"node-check-interval"`
+	BotToken          string        `mapstructure:"token"`
+	RequestCoolDown   time.Duration `mapstructure:"cooldown"`
+	SecureConnection  bool          `mapstructure:"secure"`
+	// DailyCap is the max amount a user without a tier can receive in 24 hours, 0 means no cap
+	DailyCap uint64       `mapstructure:"daily-cap"`
+	Tiers    []TierConfig `mapstructure:"tiers"`
+	// AdminRoles are the role IDs allowed to change the settings of a guild, unless the guild sets its own
+	AdminRoles []string `mapstructure:"admin-roles"`
+	// Language is the default language of the replies
+	Language string `mapstructure:"language"`
+	// AuditLog is the path of the payout audit log, empty disables it
+	AuditLog string `mapstructure:"audit-log"`
+
+	// LogLevel is the minimum level logged, one of debug, info, warn or error
+	LogLevel string `mapstructure:"log-level"`
+	// LogJSON writes the log as JSON objects
+	LogJSON bool `mapstructure:"log-json"`
+	// LogMessages logs the content of messages and replies
+	LogMessages bool `mapstructure:"log-messages"`
+
+	// HTTPListen is the listen address of the REST API, empty disables it
+	HTTPListen string   `mapstructure:"http-listen"`
+	APIKeys    []string `mapstructure:"api-keys"`
+	// HTTPForwardedHeader is the header holding the client IP when behind a proxy, e.g. X-Forwarded-For
+	HTTPForwardedHeader string `mapstructure:"http-forwarded-header"`
+	// HTTPProxyHops is the number of trusted proxies appending to HTTPForwardedHeader, 0 ignores the header
+	HTTPProxyHops int `mapstructure:"http-proxy-hops"`
+	// WebEnabled serves the faucet web page on the http api address
+	WebEnabled bool `mapstructure:"web-enabled"`
+	// MetricsListen is the listen address of the Prometheus /metrics endpoint, empty disables it
+	MetricsListen string `mapstructure:"metrics-listen"`
+
+	// TelegramToken enables the telegram frontend
+	TelegramToken  string `mapstructure:"telegram-token"`
+	TelegramAPIURL string `mapstructure:"telegram-api-url"`
+
+	// payout tx tracking
+	TxPollInterval time.Duration `mapstructure:"tx-poll-interval"`
+	TxTrackTimeout time.Duration `mapstructure:"tx-track-timeout"`
+	// TxNotifyDM sends discord requesters a direct message once their payout is final
+	TxNotifyDM bool `mapstructure:"tx-notify-dm"`
+
+	// StateFile keeps cooldowns, recent payouts and tracked txs across restarts, empty disables it
+	StateFile string `mapstructure:"state-file"`
+	// ShutdownTimeout bounds the graceful shutdown
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
+
+	// low balance alerts
+	AlertChannel         string        `mapstructure:"alert-channel"`
+	AlertUsers           []string      `mapstructure:"alert-users"`
+	BalanceWarning       uint64        `mapstructure:"balance-warning"`
+	BalanceCritical      uint64        `mapstructure:"balance-critical"`
+	BalanceHysteresis    uint64        `mapstructure:"balance-hysteresis"`
+	BalanceCheckInterval time.Duration `mapstructure:"balance-check-interval"`
+
+	// automatic hot wallet refill from a reserve wallet
+	ReserveMnemonic     string        `mapstructure:"reserve-mnemonic"`
+	ReservePrivateKey   string        `mapstructure:"reserve-priv-key"`
+	RefillThreshold     uint64        `mapstructure:"refill-threshold"`
+	RefillAmount        uint64        `mapstructure:"refill-amount"`
+	RefillDailyMax      uint64        `mapstructure:"refill-daily-max"`
+	RefillCheckInterval time.Duration `mapstructure:"refill-check-interval"`
+
+	// Simulate runs against an in-memory ledger instead of a node
+	Simulate bool `mapstructure:"simulate"`
+	// SimBalance funds the faucet and reserve wallets on the simulated ledger
+	SimBalance       uint64        `mapstructure:"sim-balance"`
+	SimLayerDuration time.Duration `mapstructure:"sim-layer-duration"`
+	// SimFailRate is the probability of a simulated node call failing
+	SimFailRate float64 `mapstructure:"sim-fail-rate"`
+
+	// DryRun handles fund requests without submitting the transfers
+	DryRun bool `mapstructure:"dry-run"`
+
+	// ConfigFile is the file the config was loaded from, empty without one
+	ConfigFile string `mapstructure:"-"`
 }
 
 func DefaultConfig() *BaseConfig {
 	return &BaseConfig{}
 }
 
-const defaultConfigFileName = "config.toml"
+const (
+	defaultConfigFileName = "config.toml"
+	defaultFee            = 50
+)
+
+func (c BaseConfig) fee() uint64 {
+	if c.Fee == 0 {
+		return defaultFee
+	}
+	return c.Fee
+}
 
-// LoadConfigFromFile tries to load configuration file if the config parameter was specified
-func LoadConfigFromFile() (*BaseConfig, error) {
-	fileLocation := viper.GetString("config")
+// LoadConfigFromFile loads the config file named by the config flag or by
+// TAPBOT_CONFIG, config.toml by default. Every config key is taken from the
+// first of: a flag set in flags, its TAPBOT_ environment variable, the config
+// file and DefaultConfig. flags may be nil.
+func LoadConfigFromFile(flags *pflag.FlagSet) (*BaseConfig, error) {
 	vip := viper.New()
-	// read in default config if passed as param using viper
-	if err := LoadConfig(fileLocation, vip); err != nil {
-		fmt.Println(fmt.Sprintf("couldn't load config file at location: %s switching to defaults \n error: %v.",
-			fileLocation, err))
-		// return err
+	vip.SetEnvPrefix(envPrefix)
+	vip.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	vip.AutomaticEnv()
+	if flags != nil {
+		if err := vip.BindPFlags(flags); err != nil {
+			return nil, fmt.Errorf("failed to bind flags %v", err)
+		}
+	}
+
+	if fileLocation := vip.GetString("config"); fileLocation != "" {
+		// a config file asked for has to be there
+		vip.SetConfigFile(fileLocation)
+		if err := vip.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %v", err)
+		}
+	} else if err := LoadConfig("", vip); err != nil {
+		logger.Warn("couldn't load config file, using flags, environment and defaults", zap.String("path", defaultConfigFileName), zap.Error(err))
 	}
 
 	conf := DefaultConfig()
@@ -39,7 +155,14 @@ func LoadConfigFromFile() (*BaseConfig, error) {
 	// load config if it was loaded to our viper
 	err := vip.Unmarshal(&conf)
 	if err != nil {
-		fmt.Println(fmt.Sprintf("Failed to parse config %v", err))
+		logger.Error("failed to parse config", zap.Error(err))
+
+		return nil, err
+	}
+
+	conf.ConfigFile = vip.ConfigFileUsed()
+	if err := conf.resolveSecrets(); err != nil {
+		logger.Error("failed to resolve config secrets", zap.Error(err))
 
 		return nil, err
 	}
@@ -58,7 +181,7 @@ func LoadConfig(fileLocation string, vip *viper.Viper) (err error) {
 	if err != nil {
 		if fileLocation != defaultConfigFileName {
 			//log.Warning("failed loading config from %v trying %v. error %v", fileLocation, defaultConfigFileName, err)
-			fmt.Println(fmt.Sprintf("Failed to parse config %v", err))
+			logger.Warn("failed to parse config", zap.String("path", fileLocation), zap.Error(err))
 			vip.SetConfigFile(defaultConfigFileName)
 			err = vip.ReadInConfig()
 		}
@@ -70,4 +193,3 @@ func LoadConfig(fileLocation string, vip *viper.Viper) (err error) {
 
 	return nil
 }
-
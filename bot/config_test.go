@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func setEnv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "tapbot.toml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+transfer-amount = 100
+cooldown = "1h"
+server = "file:9092"
+api-keys = ["file"]
+`)
+	setEnv(t, "TAPBOT_CONFIG", path)
+	setEnv(t, "TAPBOT_COOLDOWN", "2h")
+	setEnv(t, "TAPBOT_SERVER", "env:9092")
+	setEnv(t, "TAPBOT_DAILY_CAP", "500")
+
+	flags := ConfigFlags("test")
+	if err := flags.Parse([]string{"--server", "flag:9092", "--dry-run", "--api-keys", "a,b"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFromFile(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TransferAmount != 100 {
+		t.Errorf("transfer amount = %v, want 100 from the file", cfg.TransferAmount)
+	}
+	if cfg.RequestCoolDown != 2*time.Hour {
+		t.Errorf("cooldown = %v, want 2h from the environment", cfg.RequestCoolDown)
+	}
+	if cfg.DailyCap != 500 {
+		t.Errorf("daily cap = %v, want 500 from the environment", cfg.DailyCap)
+	}
+	if cfg.Server != "flag:9092" || !cfg.DryRun {
+		t.Errorf("server = %v, dry run = %v, want the flags", cfg.Server, cfg.DryRun)
+	}
+	if !reflect.DeepEqual(cfg.APIKeys, []string{"a", "b"}) {
+		t.Errorf("api keys = %v, want [a b]", cfg.APIKeys)
+	}
+	if cfg.Simulate || cfg.WalletCount != 0 {
+		t.Errorf("unset keys are not the defaults: %+v", cfg)
+	}
+}
+
+func TestConfigFileFlag(t *testing.T) {
+	path := writeConfigFile(t, `cooldown = "1h"`)
+	flags := ConfigFlags("test")
+	if err := flags.Parse([]string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFromFile(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RequestCoolDown != time.Hour {
+		t.Errorf("cooldown = %v, want 1h", cfg.RequestCoolDown)
+	}
+
+	flags = ConfigFlags("test")
+	if err := flags.Parse([]string{"--config", filepath.Join(t.TempDir(), "missing.toml")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFromFile(flags); err == nil {
+		t.Error("missing config file accepted")
+	}
+}
+
+func TestConfigFlagsCoverConfig(t *testing.T) {
+	// the tables only settable in the config file, see flagUsage
+	fileOnly := map[string]bool{"networks": true, "tiers": true}
+
+	flags := ConfigFlags("test")
+	fields := reflect.TypeOf(BaseConfig{})
+	for i := 0; i < fields.NumField(); i++ {
+		key := fields.Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" || fileOnly[key] {
+			continue
+		}
+		if flags.Lookup(key) == nil {
+			t.Errorf("no flag for %v of field %v, add it to flagUsage", key, fields.Field(i).Name)
+		}
+	}
+	for key := range flagUsage {
+		if flags.Lookup(key) == nil {
+			t.Errorf("no flag for %v, its field type is not supported", key)
+		}
+	}
+}
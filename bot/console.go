@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const consolePrompt = "> "
+
+// ConsoleFrontend reads commands line by line from in and writes the replies to out.
+// It goes through the same dispatch as the other frontends and is meant for
+// exercising commands locally. A line ":roles <id> ..." sets the role IDs used
+// for the following requests, to try out payout tiers, and ":guild <id>" the
+// guild they are sent in.
+type ConsoleFrontend struct {
+	in    io.Reader
+	out   io.Writer
+	roles []string
+	guild string
+	done  chan struct{}
+}
+
+func NewConsoleFrontend(in io.Reader, out io.Writer) *ConsoleFrontend {
+	return &ConsoleFrontend{in: in, out: out, done: make(chan struct{})}
+}
+
+func (c *ConsoleFrontend) Name() string {
+	return "console"
+}
+
+// Start processes input in the background until it is exhausted, see Done.
+func (c *ConsoleFrontend) Start(h Handler) error {
+	go c.run(h)
+	return nil
+}
+
+// Close is a no-op, the console stops at the end of its input.
+func (c *ConsoleFrontend) Close() error {
+	return nil
+}
+
+// Done is closed when the input is exhausted.
+func (c *ConsoleFrontend) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ConsoleFrontend) run(h Handler) {
+	defer close(c.done)
+	scanner := bufio.NewScanner(c.in)
+	fmt.Fprint(c.out, consolePrompt)
+	for scanner.Scan() {
+		c.handleLine(h, scanner.Text())
+		fmt.Fprint(c.out, consolePrompt)
+	}
+	fmt.Fprintln(c.out)
+}
+
+// TxUpdated prints the final state of payouts requested from the console.
+func (c *ConsoleFrontend) TxUpdated(u *TxUpdate) {
+	if u.Request == nil || u.Request.Frontend != c.Name() || !u.Final {
+		return
+	}
+	fmt.Fprintln(c.out, u.Text())
+}
+
+func (c *ConsoleFrontend) handleLine(h Handler, line string) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return
+	}
+	if args[0] == ":roles" {
+		c.roles = args[1:]
+		fmt.Fprintf(c.out, "roles set to %v\n", c.roles)
+		return
+	}
+	if args[0] == ":guild" {
+		c.guild = strings.Join(args[1:], "")
+		fmt.Fprintf(c.out, "guild set to %q\n", c.guild)
+		return
+	}
+
+	resp, err := h.Handle(&Request{
+		Frontend:      c.Name(),
+		RequesterID:   "console",
+		RequesterName: "console",
+		Roles:         c.roles,
+		GuildID:       c.guild,
+		Args:          args,
+	})
+	if err != nil {
+		fmt.Fprintln(c.out, "error:", err)
+		return
+	}
+	fmt.Fprintln(c.out, resp.Text)
+}
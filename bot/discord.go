@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DiscordFrontend serves bot commands sent as discord messages.
+type DiscordFrontend struct {
+	session *discordgo.Session
+	handler Handler
+	txDMs   bool
+
+	// mu guards degraded, the networks whose node connection is down
+	mu       sync.Mutex
+	degraded map[string]bool
+}
+
+// reactions on fund requests showing the state of the payout, see $help
+const (
+	reactionPending   = "💸"
+	reactionConfirmed = "✅"
+	reactionFailed    = "🚫"
+)
+
+// NewDiscordFrontend returns a discord frontend using session, the session is
+// opened by Start.
+func NewDiscordFrontend(session *discordgo.Session) *DiscordFrontend {
+	return &DiscordFrontend{session: session, degraded: make(map[string]bool)}
+}
+
+func (d *DiscordFrontend) Name() string {
+	return "discord"
+}
+
+// Start registers the message handler and opens the discord websocket.
+func (d *DiscordFrontend) Start(h Handler) error {
+	d.handler = h
+	d.session.AddHandler(d.onMessage)
+	d.session.AddHandler(d.onReady)
+	return d.session.Open()
+}
+
+func (d *DiscordFrontend) Close() error {
+	return d.session.Close()
+}
+
+func (d *DiscordFrontend) onMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Ignore all messages created by the bot itself
+	// This isn't required in this specific example but it's a good practice.
+	if m.Author.ID == s.State.User.ID {
+		return
+	}
+
+	req := &Request{
+		Frontend:      d.Name(),
+		RequesterID:   m.Author.ID,
+		RequesterName: m.Author.Username,
+		Roles:         memberRoles(s, m),
+		GuildID:       m.GuildID,
+		ChannelID:     m.ChannelID,
+		MessageID:     m.ID,
+		Args:          strings.Fields(m.Content),
+	}
+
+	resp, err := d.handler.Handle(req)
+	if err == ErrUnknownCommand {
+		return
+	}
+	var text string
+	if err != nil {
+		// only fund request and admin command failures are reported back to the requester
+		if !isTransferRequest(req) && !isGuildCommand(req) {
+			return
+		}
+		text = err.Error()
+	} else {
+		text = resp.Text
+	}
+	if _, err := s.ChannelMessageSend(m.ChannelID, text); err != nil {
+		req.Logger().Warn("failed to send reply", zap.Error(err))
+	}
+}
+
+// isTransferRequest returns true if req is a fund request rather than a $command.
+func isTransferRequest(req *Request) bool {
+	return len(req.Args) > 0 && strings.HasPrefix(strings.ToLower(req.Args[0]), "0x")
+}
+
+// memberRoles returns the guild role IDs of the message author.
+func memberRoles(s *discordgo.Session, m *discordgo.MessageCreate) []string {
+	if m.Member != nil {
+		return m.Member.Roles
+	}
+	if m.GuildID == "" {
+		return nil
+	}
+	member, err := s.State.Member(m.GuildID, m.Author.ID)
+	if err != nil {
+		member, err = s.GuildMember(m.GuildID, m.Author.ID)
+		if err != nil {
+			logger.Warn("failed to read member roles", zap.String("user", m.Author.ID), zap.Error(err))
+			return nil
+		}
+	}
+	return member.Roles
+}
+
+// SetTxDMs enables direct messages to requesters once their payout is final.
+func (d *DiscordFrontend) SetTxDMs(enabled bool) {
+	d.txDMs = enabled
+}
+
+// TxUpdated reacts to the fund request message with the payout state.
+func (d *DiscordFrontend) TxUpdated(u *TxUpdate) {
+	req := u.Request
+	if req == nil || req.Frontend != d.Name() || req.MessageID == "" {
+		return
+	}
+	if !u.Final {
+		if err := d.session.MessageReactionAdd(req.ChannelID, req.MessageID, reactionPending); err != nil {
+			req.Logger().Warn("failed to add reaction", zap.Error(err))
+		}
+		return
+	}
+
+	_ = d.session.MessageReactionRemove(req.ChannelID, req.MessageID, reactionPending, "@me")
+	reaction := reactionFailed
+	if u.Confirmed() {
+		reaction = reactionConfirmed
+	}
+	if err := d.session.MessageReactionAdd(req.ChannelID, req.MessageID, reaction); err != nil {
+		req.Logger().Warn("failed to add reaction", zap.Error(err))
+	}
+	if d.txDMs {
+		if err := d.NotifyUser(req.RequesterID, u.Text()); err != nil {
+			req.Logger().Warn("failed to send payout state", zap.Error(err))
+		}
+	}
+}
+
+// ConnStateChanged shows the networks with a degraded node connection in the
+// presence of the bot.
+func (d *DiscordFrontend) ConnStateChanged(network string, state ConnState) {
+	d.mu.Lock()
+	if state == ConnDegraded {
+		d.degraded[network] = true
+	} else {
+		delete(d.degraded, network)
+	}
+	d.mu.Unlock()
+	d.updatePresence()
+}
+
+// onReady sets the presence again whenever the session (re)connects.
+func (d *DiscordFrontend) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	d.updatePresence()
+}
+
+func (d *DiscordFrontend) updatePresence() {
+	d.mu.Lock()
+	var networks []string
+	for network := range d.degraded {
+		networks = append(networks, network)
+	}
+	d.mu.Unlock()
+	sort.Strings(networks)
+
+	status := discordgo.UpdateStatusData{Status: "online", Activities: []*discordgo.Activity{}}
+	if len(networks) > 0 {
+		status.Status = "idle"
+		status.Activities = []*discordgo.Activity{{
+			Name: "degraded, reconnecting to " + strings.Join(networks, ", "),
+			Type: discordgo.ActivityTypeGame,
+		}}
+	}
+	if err := d.session.UpdateStatusComplex(status); err != nil && err != discordgo.ErrWSNotFound {
+		logger.Warn("failed to update presence", zap.Error(err))
+	}
+}
+
+func (d *DiscordFrontend) NotifyChannel(channelID string, msg string) error {
+	_, err := d.session.ChannelMessageSend(channelID, msg)
+	return err
+}
+
+func (d *DiscordFrontend) NotifyUser(userID string, msg string) error {
+	ch, err := d.session.UserChannelCreate(userID)
+	if err != nil {
+		return fmt.Errorf("failed to open DM channel with %v: %v", userID, err)
+	}
+	_, err = d.session.ChannelMessageSend(ch.ID, msg)
+	return err
+}
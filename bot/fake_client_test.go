@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+type fakeTransfer struct {
+	from      gosmtypes.Address
+	to        gosmtypes.Address
+	nonce     uint64
+	amount    uint64
+	gasPrice  uint64
+	gasLimit  uint64
+	projected uint64
+}
+
+// fakeClient is a Client returning canned node responses and recording transfers.
+type fakeClient struct {
+	mu sync.Mutex
+
+	status    *apitypes.NodeStatus
+	statusErr error
+
+	accounts   map[gosmtypes.Address]*apitypes.Account
+	accountErr error
+
+	// txState is the state returned by Transfer, MEMPOOL if unset
+	txState     apitypes.TransactionState_TransactionState
+	transferErr error
+	transfers   []fakeTransfer
+
+	txs     map[string]*apitypes.Transaction
+	meshTxs []*apitypes.MeshTransaction
+	// states are the polled states of txs, by id
+	states map[string]apitypes.TransactionState_TransactionState
+	polls  int
+
+	// subscribe serves SubscribeTransactionState, the stream is unsupported if nil
+	subscribe func(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error)
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		status: &apitypes.NodeStatus{
+			IsSynced:       true,
+			ConnectedPeers: 5,
+			TopLayer:       &apitypes.LayerNumber{Number: 10},
+		},
+		accounts: make(map[gosmtypes.Address]*apitypes.Account),
+		txState:  apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL,
+		txs:      make(map[string]*apitypes.Transaction),
+		states:   make(map[string]apitypes.TransactionState_TransactionState),
+	}
+}
+
+// setAccount sets the current and projected state of address.
+func (c *fakeClient) setAccount(address gosmtypes.Address, balance, counter, projectedBalance, projectedCounter uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accounts[address] = &apitypes.Account{
+		AccountId:      &apitypes.AccountId{Address: address.Bytes()},
+		StateCurrent:   &apitypes.AccountState{Counter: counter, Balance: &apitypes.Amount{Value: balance}},
+		StateProjected: &apitypes.AccountState{Counter: projectedCounter, Balance: &apitypes.Amount{Value: projectedBalance}},
+	}
+}
+
+// setDown makes the node calls of c fail with err, or succeed again if err is nil.
+func (c *fakeClient) setDown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statusErr, c.accountErr = err, err
+}
+
+func (c *fakeClient) sent() []fakeTransfer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]fakeTransfer{}, c.transfers...)
+}
+
+func (c *fakeClient) NodeStatus() (*apitypes.NodeStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status, c.statusErr
+}
+
+func (c *fakeClient) AccountState(address gosmtypes.Address) (*apitypes.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accountErr != nil {
+		return nil, c.accountErr
+	}
+	if acc, ok := c.accounts[address]; ok {
+		return acc, nil
+	}
+	return &apitypes.Account{
+		AccountId:      &apitypes.AccountId{Address: address.Bytes()},
+		StateCurrent:   &apitypes.AccountState{Balance: &apitypes.Amount{}},
+		StateProjected: &apitypes.AccountState{Balance: &apitypes.Amount{}},
+	}, nil
+}
+
+// Transfer records the transfer and applies it to the projected state of the
+// sender like a node accepting it to its mempool.
+func (c *fakeClient) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transferErr != nil {
+		return nil, c.transferErr
+	}
+	from := gosmtypes.BytesToAddress(key.Public().(ed25519.PublicKey))
+	t := fakeTransfer{from: from, to: recipient, nonce: nonce, amount: amount, gasPrice: gasPrice, gasLimit: gasLimit}
+	if acc, ok := c.accounts[from]; ok {
+		t.projected = acc.StateProjected.Counter
+		if c.txState == apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL {
+			acc.StateProjected.Counter++
+			acc.StateProjected.Balance.Value -= amount + gasPrice
+		}
+	}
+	c.transfers = append(c.transfers, t)
+	id := []byte{byte(len(c.transfers))}
+	return &apitypes.TransactionState{Id: &apitypes.TransactionId{Id: id}, State: c.txState}, nil
+}
+
+// TransactionState answers like the smrepl wallet backend: the node only
+// returns the tx with includeTx set and the backend indexes the returned txs
+// unchecked, so a call without includeTx panics.
+func (c *fakeClient) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.polls++
+	state := &apitypes.TransactionState{Id: &apitypes.TransactionId{Id: txId}, State: apitypes.TransactionState_TRANSACTION_STATE_PROCESSED}
+	tx, ok := c.txs[string(txId)]
+	if s, tracked := c.states[string(txId)]; tracked {
+		state.State = s
+		tx, ok = &apitypes.Transaction{Id: state.Id}, true
+	}
+	if !ok {
+		return nil, nil, status.Error(codes.NotFound, "transaction not found")
+	}
+	var txs []*apitypes.Transaction
+	if includeTx {
+		txs = append(txs, tx)
+	}
+	return state, txs[0], nil
+}
+
+func (c *fakeClient) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.meshTxs, uint32(len(c.meshTxs)), nil
+}
+
+func (c *fakeClient) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	c.mu.Lock()
+	subscribe := c.subscribe
+	c.mu.Unlock()
+	if subscribe == nil {
+		return nil, ErrStreamUnsupported
+	}
+	return subscribe(ctx, txId)
+}
+
+func (c *fakeClient) setState(txId []byte, state apitypes.TransactionState_TransactionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[string(txId)] = state
+}
+
+func (c *fakeClient) pollCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.polls
+}
+
+// fakeClock is a settable clock for cooldowns and daily caps.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"github.com/spf13/pflag"
+	"reflect"
+	"time"
+)
+
+// envPrefix prefixes the environment variables setting config keys, e.g.
+// TAPBOT_TRANSFER_AMOUNT sets transfer-amount.
+const envPrefix = "TAPBOT"
+
+// flagUsage describes the config keys settable on the command line. Lists and
+// tables, such as networks and tiers, can only be set in the config file.
+var flagUsage = map[string]string{
+	"network":                "name of the default network",
+	"mnemonic":               "mnemonic to derive the faucet wallets from",
+	"wallet-count":           "number of hot wallets derived from the mnemonic",
+	"pub-key":                "address of the faucet wallet",
+	"priv-key":               "private key of the faucet wallet, not needed with a mnemonic",
+	"transfer-amount":        "amount sent per fund request",
+	"fee":                    "gas price paid per payout",
+	"server":                 "spacemesh api grpc server host and port",
+	"servers":                "pool of api servers used instead of server",
+	"node-check-interval":    "how often the node connection is checked",
+	"token":                  "discord bot token",
+	"cooldown":               "time a requester waits between fund requests",
+	"secure":                 "connect to the node with TLS",
+	"daily-cap":              "max amount a requester without a tier receives in 24 hours, 0 means no cap",
+	"admin-roles":            "role IDs allowed to change the faucet settings of a discord server",
+	"language":               "default language of the replies",
+	"audit-log":              "path of the payout audit log",
+	"log-level":              "minimum level logged: debug, info, warn or error",
+	"log-json":               "log JSON objects instead of plain lines",
+	"log-messages":           "log the content of messages and replies",
+	"http-listen":            "listen address of the REST API",
+	"api-keys":               "keys of REST API clients rate limited per key instead of per IP",
+	"http-forwarded-header":  "header holding the client IP when behind a proxy",
+	"http-proxy-hops":        "number of trusted proxies appending to http-forwarded-header",
+	"web-enabled":            "serve the faucet web page on the REST API address",
+	"metrics-listen":         "listen address of the Prometheus /metrics endpoint",
+	"telegram-token":         "telegram bot token",
+	"telegram-api-url":       "telegram Bot API server",
+	"tx-poll-interval":       "how often the state of tracked payouts is polled",
+	"tx-track-timeout":       "how long payouts are tracked",
+	"tx-notify-dm":           "send discord requesters a direct message once their payout is final",
+	"state-file":             "file keeping cooldowns, recent payouts and tracked txs across restarts",
+	"shutdown-timeout":       "deadline of the graceful shutdown",
+	"alert-channel":          "discord channel receiving operator alerts",
+	"alert-users":            "users mentioned in operator alerts",
+	"balance-warning":        "faucet balance triggering a warning alert",
+	"balance-critical":       "faucet balance triggering a critical alert",
+	"balance-hysteresis":     "amount the balance has to recover before an alert clears",
+	"balance-check-interval": "how often the faucet balance is checked",
+	"reserve-mnemonic":       "mnemonic of the reserve wallet refilling the hot wallets",
+	"reserve-priv-key":       "private key of the reserve wallet",
+	"refill-threshold":       "hot wallet balance triggering a refill",
+	"refill-amount":          "amount sent per refill",
+	"refill-daily-max":       "max amount refilled in 24 hours",
+	"refill-check-interval":  "how often the hot wallets are checked for a refill",
+	"simulate":               "run against an in-memory ledger instead of a node",
+	"sim-balance":            "balance of the faucet and reserve wallets on the simulated ledger",
+	"sim-layer-duration":     "layer duration of the simulated ledger",
+	"sim-fail-rate":          "probability of a simulated node call failing",
+	"dry-run":                "check fund requests without submitting the transfers",
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ConfigFlags returns the command line flags of the bot: --config naming the
+// config file and a flag for every config key in flagUsage, defaulting to
+// DefaultConfig. Pass the parsed flags to LoadConfigFromFile.
+func ConfigFlags(name string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ExitOnError)
+	fs.SortFlags = false
+	fs.String("config", "", "path of the config file (default "+defaultConfigFileName+")")
+
+	defaults := reflect.ValueOf(DefaultConfig()).Elem()
+	for i := 0; i < defaults.NumField(); i++ {
+		key := defaults.Type().Field(i).Tag.Get("mapstructure")
+		usage, ok := flagUsage[key]
+		if !ok {
+			continue
+		}
+		v := defaults.Field(i)
+		switch {
+		case v.Type() == durationType:
+			fs.Duration(key, time.Duration(v.Int()), usage)
+		case v.Kind() == reflect.String:
+			fs.String(key, v.String(), usage)
+		case v.Kind() == reflect.Bool:
+			fs.Bool(key, v.Bool(), usage)
+		case v.Kind() == reflect.Int:
+			fs.Int(key, int(v.Int()), usage)
+		case v.Kind() == reflect.Uint64:
+			fs.Uint64(key, v.Uint(), usage)
+		case v.Kind() == reflect.Float64:
+			fs.Float64(key, v.Float(), usage)
+		case v.Type() == reflect.TypeOf([]string(nil)):
+			fs.StringSlice(key, v.Interface().([]string), usage)
+		}
+	}
+	return fs
+}
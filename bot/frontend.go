@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUnknownCommand is returned by Handle for messages that are not bot commands.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// CoolDownError is returned when funds are requested before a cooldown or daily cap expired.
+type CoolDownError struct {
+	Msg string
+	// Retry is how long the requester has to wait before the next request.
+	Retry time.Duration
+}
+
+func (e *CoolDownError) Error() string {
+	return e.Msg
+}
+
+// CommandTransfer is the command name of fund requests, which are sent as a bare address.
+const CommandTransfer = "transfer"
+
+// Request is a transport neutral command sent to the bot by a frontend.
+type Request struct {
+	// ID correlates the logs of the request, it is set by LogRequests.
+	ID string
+	// Frontend is the name of the frontend the request came from.
+	Frontend string
+	// RequesterID identifies the requester within the frontend, cooldowns are keyed by it.
+	RequesterID   string
+	RequesterName string
+	// Roles are the requester's role IDs used to resolve the payout tier.
+	Roles     []string
+	GuildID   string
+	ChannelID string
+	MessageID string
+	// Args holds the command followed by its arguments.
+	Args []string
+	// Guild is the config of the guild the request was sent in, set by GuildHandler.
+	Guild *GuildConfig `json:"-"`
+}
+
+// Response is the structured result of a Request.
+type Response struct {
+	Command string `json:"command"`
+	// Network is the network the request was served on.
+	Network string `json:"network,omitempty"`
+	// Text is the human readable rendering of the result.
+	Text string `json:"text"`
+	// Data holds the typed result, one of the *Result types below.
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Handler processes requests from frontends.
+type Handler interface {
+	Handle(req *Request) (*Response, error)
+}
+
+// Frontend is a transport delivering requests to a Handler and rendering its responses.
+type Frontend interface {
+	Name() string
+	Start(h Handler) error
+	Close() error
+}
+
+type BalanceResult struct {
+	Address string `json:"address"`
+	Balance uint64 `json:"balance"`
+}
+
+type FaucetAddressResult struct {
+	Address   string   `json:"address"`
+	Addresses []string `json:"addresses"`
+}
+
+type FaucetStatusResult struct {
+	Address  string `json:"address"`
+	Balance  uint64 `json:"balance"`
+	Synced   bool   `json:"synced"`
+	Peers    uint64 `json:"peers"`
+	TopLayer uint32 `json:"top_layer"`
+	// Wallets holds the state of every faucet wallet, Balance is their total.
+	Wallets []WalletStatus `json:"wallets"`
+	// Confirmed and Failed count the tracked payouts since the bot started.
+	Confirmed uint64 `json:"confirmed"`
+	Failed    uint64 `json:"failed"`
+	// Degraded is set while the connection to the node is down, the node
+	// and wallet fields are not set then.
+	Degraded bool `json:"degraded"`
+}
+
+type TxResult struct {
+	ID     string `json:"id,omitempty"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount uint64 `json:"amount"`
+	Fee    uint64 `json:"fee"`
+	Layer  uint32 `json:"layer,omitempty"`
+	State  string `json:"state,omitempty"`
+}
+
+type TxsResult struct {
+	Address string     `json:"address"`
+	Txs     []TxResult `json:"txs"`
+}
+
+type TierResult struct {
+	Tier        string        `json:"tier"`
+	Amount      uint64        `json:"amount"`
+	CoolDown    time.Duration `json:"cooldown"`
+	DailyCap    uint64        `json:"daily_cap"`
+	DailyUsed   uint64        `json:"daily_used"`
+	NextRequest time.Time     `json:"next_request,omitempty"`
+}
+
+type TransferResult struct {
+	Address string    `json:"address"`
+	Amount  uint64    `json:"amount"`
+	Tier    string    `json:"tier"`
+	TxID    string    `json:"tx_id"`
+	State   string    `json:"state"`
+	Time    time.Time `json:"time"`
+	// DryRun is set if the transfer was not submitted
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+type RecentPayoutsResult struct {
+	Payouts []TransferResult `json:"payouts"`
+}
+
+type NetworksResult struct {
+	Networks []string `json:"networks"`
+	Default  string   `json:"default"`
+}
@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const guildCommand = "$guild"
+
+// GuildConfig overrides the config for the requests sent in a discord guild.
+// Unset fields fall back to the config. TransferAmount and RequestCoolDown
+// apply to requesters without a tier, Commands are the enabled commands and
+// Channels the channels the bot answers in, all if empty.
+type GuildConfig struct {
+	TransferAmount  uint64        `json:"transfer_amount,omitempty"`
+	RequestCoolDown time.Duration `json:"cooldown,omitempty"`
+	Commands        []string      `json:"commands,omitempty"`
+	Channels        []string      `json:"channels,omitempty"`
+	AdminRoles      []string      `json:"admin_roles,omitempty"`
+	Language        string        `json:"language,omitempty"`
+}
+
+// language returns the language of the replies, nil safe.
+func (g *GuildConfig) language() string {
+	if g == nil || g.Language == "" {
+		return defaultLanguage
+	}
+	return g.Language
+}
+
+// withGuild returns c with the overrides of g applied, g may be nil. Guild
+// admins can only lower the amount and lengthen the cooldown of c.
+func (c BaseConfig) withGuild(g *GuildConfig) BaseConfig {
+	if g == nil {
+		return c
+	}
+	if g.TransferAmount > 0 && g.TransferAmount < c.TransferAmount {
+		c.TransferAmount = g.TransferAmount
+	}
+	if g.RequestCoolDown > c.RequestCoolDown {
+		c.RequestCoolDown = g.RequestCoolDown
+	}
+	return c
+}
+
+// guildCommands are the command names which can be enabled per guild.
+var guildCommands = []string{
+	CommandTransfer,
+	strings.TrimPrefix(balance, "$"),
+	strings.TrimPrefix(help, "$"),
+	strings.TrimPrefix(dumpTxs, "$"),
+	strings.TrimPrefix(faucetStatus, "$"),
+	strings.TrimPrefix(faucetAddr, "$"),
+	strings.TrimPrefix(txInfo, "$"),
+	strings.TrimPrefix(myTier, "$"),
+	strings.TrimPrefix(recentPays, "$"),
+	strings.TrimPrefix(networks, "$"),
+}
+
+var errNotGuildAdmin = errors.New("only server admins can change the faucet settings of this server")
+
+// GuildHandler applies the per guild config to the requests passed to h and
+// serves the $guild admin commands editing it. The guild configs are kept in
+// the state store, if there is one.
+type GuildHandler struct {
+	h     Handler
+	store *StateStore
+
+	// mu guards the guilds and the defaults, which are replaced on a config reload
+	mu         sync.Mutex
+	adminRoles []string
+	language   string
+	guilds     map[string]*GuildConfig
+}
+
+// NewGuildHandler returns h with the guild configs saved in store applied,
+// store may be nil. The admin roles and language of cfg are the defaults of
+// every guild.
+func NewGuildHandler(h Handler, cfg BaseConfig, store *StateStore) *GuildHandler {
+	return &GuildHandler{
+		h:          h,
+		store:      store,
+		adminRoles: cfg.AdminRoles,
+		language:   cfg.Language,
+		guilds:     store.Guilds(),
+	}
+}
+
+func (g *GuildHandler) Handle(req *Request) (*Response, error) {
+	if len(req.Args) > 0 && req.Args[0] == guildCommand {
+		return g.handleAdmin(req)
+	}
+	guild := g.guild(req.GuildID)
+
+	if len(guild.Channels) > 0 && !contains(guild.Channels, req.ChannelID) {
+		return nil, ErrUnknownCommand
+	}
+	// chat messages are left to h, which ignores them
+	if len(guild.Commands) > 0 && len(req.Args) > 0 && (isTransferRequest(req) || strings.HasPrefix(req.Args[0], "$")) {
+		args, _ := splitNetArg(req.Args)
+		if len(args) > 0 {
+			if command := requestCommand(&Request{Args: args}); !contains(guild.Commands, command) {
+				return nil, fmt.Errorf("%v is disabled in this server", command)
+			}
+		}
+	}
+
+	withGuild := *req
+	withGuild.Guild = guild
+	return g.h.Handle(&withGuild)
+}
+
+// guild returns a copy of the config of guild id with the defaults applied.
+func (g *GuildHandler) guild(id string) *GuildConfig {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	guild := GuildConfig{}
+	if saved, ok := g.guilds[id]; ok && id != "" {
+		guild = *saved
+	}
+	if len(guild.AdminRoles) == 0 {
+		guild.AdminRoles = g.adminRoles
+	}
+	if guild.Language == "" {
+		guild.Language = g.language
+	}
+	return &guild
+}
+
+// SetConfig replaces the default admin roles and language of the guilds.
+func (g *GuildHandler) SetConfig(cfg BaseConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.adminRoles = cfg.AdminRoles
+	g.language = cfg.Language
+}
+
+// handleAdmin serves "$guild" showing the config of the guild, "$guild set
+// <key> <value>..." and "$guild reset [key]".
+func (g *GuildHandler) handleAdmin(req *Request) (*Response, error) {
+	if req.GuildID == "" {
+		return nil, fmt.Errorf("%v only works in a server", guildCommand)
+	}
+	args := req.Args[1:]
+	if len(args) == 0 {
+		return g.showGuild(req.GuildID), nil
+	}
+	if !hasAnyRole(req.Roles, g.guild(req.GuildID).AdminRoles) {
+		return nil, errNotGuildAdmin
+	}
+
+	g.mu.Lock()
+	guild := GuildConfig{}
+	if saved, ok := g.guilds[req.GuildID]; ok {
+		guild = *saved
+	}
+	g.mu.Unlock()
+
+	switch {
+	case args[0] == "set" && len(args) >= 3:
+		if err := setGuildKey(&guild, args[1], args[2:]); err != nil {
+			return nil, err
+		}
+	case args[0] == "reset" && len(args) == 1:
+		guild = GuildConfig{}
+	case args[0] == "reset" && len(args) == 2:
+		if err := setGuildKey(&guild, args[1], nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("usage: %v, %v set <key> <value>..., %v reset [key]", guildCommand, guildCommand, guildCommand)
+	}
+
+	g.mu.Lock()
+	g.guilds[req.GuildID] = &guild
+	g.mu.Unlock()
+	if g.store != nil {
+		g.store.SetGuild(req.GuildID, &guild)
+		if err := g.store.Save(); err != nil {
+			req.Logger().Error("failed to save guild config", zap.Error(err))
+			return nil, fmt.Errorf("the settings are applied but could not be saved, they are lost on restart")
+		}
+	}
+	req.Logger().Info("guild config changed", zap.String("guild", req.GuildID), zap.Strings("change", args))
+	return g.showGuild(req.GuildID), nil
+}
+
+func (g *GuildHandler) showGuild(id string) *Response {
+	guild := g.guild(id)
+	or := func(s, def string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	}
+	amount, cooldown := "default", "default"
+	if guild.TransferAmount > 0 {
+		amount = strconv.FormatUint(guild.TransferAmount, 10)
+	}
+	if guild.RequestCoolDown > 0 {
+		cooldown = guild.RequestCoolDown.String()
+	}
+	text := fmt.Sprintf("server settings:\namount: %v\ncooldown: %v\ncommands: %v\nchannels: %v\nadmin roles: %v\nlanguage: %v",
+		amount, cooldown,
+		or(strings.Join(guild.Commands, " "), "all"),
+		or(strings.Join(guild.Channels, " "), "all"),
+		or(strings.Join(guild.AdminRoles, " "), "none"),
+		guild.language())
+	return &Response{Command: guildCommand, Text: text, Data: guild}
+}
+
+// setGuildKey sets key of guild to values, or resets it if values is empty.
+func setGuildKey(guild *GuildConfig, key string, values []string) error {
+	switch key {
+	case "amount":
+		guild.TransferAmount = 0
+		if len(values) > 0 {
+			amount, err := strconv.ParseUint(values[0], 10, 64)
+			if err != nil || amount == 0 {
+				return fmt.Errorf("amount %q is not a positive number", values[0])
+			}
+			guild.TransferAmount = amount
+		}
+	case "cooldown":
+		guild.RequestCoolDown = 0
+		if len(values) > 0 {
+			d, err := time.ParseDuration(values[0])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("cooldown %q is not a duration, e.g. 3h", values[0])
+			}
+			guild.RequestCoolDown = d
+		}
+	case "commands":
+		for _, c := range values {
+			if !contains(guildCommands, c) {
+				return fmt.Errorf("unknown command %v, commands are: %v", c, strings.Join(guildCommands, " "))
+			}
+		}
+		guild.Commands = values
+	case "channels":
+		guild.Channels = values
+	case "admin-roles":
+		guild.AdminRoles = values
+	case "language":
+		guild.Language = ""
+		if len(values) > 0 {
+			if _, ok := helpTexts[values[0]]; !ok {
+				return fmt.Errorf("unsupported language %v, languages are: %v", values[0], strings.Join(languages(), " "))
+			}
+			guild.Language = values[0]
+		}
+	default:
+		return fmt.Errorf("unknown setting %v, settings are: amount cooldown commands channels admin-roles language", key)
+	}
+	return nil
+}
+
+// isGuildCommand returns true if req is a $guild admin command.
+func isGuildCommand(req *Request) bool {
+	return len(req.Args) > 0 && req.Args[0] == guildCommand
+}
+
+func hasAnyRole(roles, want []string) bool {
+	for _, r := range roles {
+		if contains(want, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// languages returns the supported reply languages.
+func languages() []string {
+	names := make([]string, 0, len(helpTexts))
+	for name := range helpTexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func guildRequest(roles []string, args ...string) *Request {
+	req := command(args...)
+	req.GuildID = "g1"
+	req.ChannelID = "c1"
+	req.Roles = roles
+	return req
+}
+
+func TestGuildAdmin(t *testing.T) {
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.AdminRoles = []string{"admin"}
+	b, _, _ := newTestBot(t, cfg)
+	g := NewGuildHandler(b, cfg, store)
+
+	if _, err := g.Handle(guildRequest(nil, guildCommand, "set", "amount", "500")); err != errNotGuildAdmin {
+		t.Fatalf("non admin error = %v", err)
+	}
+	for _, args := range [][]string{
+		{"set", "amount", "50"},
+		{"set", "cooldown", "2h"},
+		{"set", "admin-roles", "mod"},
+	} {
+		roles := []string{"admin"}
+		if args[1] != "admin-roles" {
+			roles = append(roles, "mod")
+		}
+		if _, err := g.Handle(guildRequest(roles, append([]string{guildCommand}, args...)...)); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	// the guild admin roles replace the default ones
+	if _, err := g.Handle(guildRequest([]string{"admin"}, guildCommand, "set", "amount", "1")); err != errNotGuildAdmin {
+		t.Errorf("default admin error = %v", err)
+	}
+	for _, args := range [][]string{
+		{"set", "amount", "0"},
+		{"set", "cooldown", "soon"},
+		{"set", "commands", "transfer", "nope"},
+		{"set", "language", "xx"},
+		{"set", "color", "red"},
+		{"frobnicate"},
+	} {
+		if _, err := g.Handle(guildRequest([]string{"mod"}, append([]string{guildCommand}, args...)...)); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+
+	req := transferRequest("alice", testAddress)
+	req.GuildID = "g1"
+	resp, err := g.Handle(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount := resp.Data.(*TransferResult).Amount; amount != 50 {
+		t.Errorf("guild amount = %v, want 50", amount)
+	}
+	resp, err = g.Handle(guildRequest(nil, myTier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier := resp.Data.(*TierResult); tier.CoolDown != 2*time.Hour {
+		t.Errorf("guild cooldown = %v, want 2h", tier.CoolDown)
+	}
+	// other guilds and direct messages get the config
+	resp, err = g.Handle(command(myTier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier := resp.Data.(*TierResult); tier.Amount != 100 {
+		t.Errorf("amount outside the guild = %v, want 100", tier.Amount)
+	}
+
+	// the guild config is saved
+	reopened, err := OpenStateStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := reopened.Guilds()["g1"]
+	if saved == nil || saved.TransferAmount != 50 || saved.RequestCoolDown != 2*time.Hour {
+		t.Errorf("saved guild config = %+v", saved)
+	}
+	g = NewGuildHandler(b, cfg, reopened)
+	resp, err = g.Handle(guildRequest(nil, guildCommand))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Text, "amount: 50") {
+		t.Errorf("restored guild config:\n%v", resp.Text)
+	}
+}
+
+func TestGuildCommandsAndChannels(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminRoles = []string{"admin"}
+	b, _, _ := newTestBot(t, cfg)
+	g := NewGuildHandler(b, cfg, nil)
+	admin := []string{"admin"}
+
+	for _, args := range [][]string{
+		{"set", "commands", "help", "balance"},
+		{"set", "channels", "c1"},
+		{"set", "language", "es"},
+	} {
+		if _, err := g.Handle(guildRequest(admin, append([]string{guildCommand}, args...)...)); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	resp, err := g.Handle(guildRequest(nil, help))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != helpTextES {
+		t.Errorf("help not in the guild language:\n%v", resp.Text)
+	}
+	if _, err := g.Handle(guildRequest(nil, testAddress)); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("disabled fund request error = %v", err)
+	}
+	if _, err := g.Handle(guildRequest(nil, "hello")); err != ErrUnknownCommand {
+		t.Errorf("chat message error = %v", err)
+	}
+
+	other := guildRequest(nil, help)
+	other.ChannelID = "c2"
+	if _, err := g.Handle(other); err != ErrUnknownCommand {
+		t.Errorf("request outside the guild channels error = %v", err)
+	}
+	// admins can not lock themselves out
+	other = guildRequest(admin, guildCommand, "reset", "channels")
+	other.ChannelID = "c2"
+	if _, err := g.Handle(other); err != nil {
+		t.Fatal(err)
+	}
+	other.Args = []string{help}
+	if _, err := g.Handle(other); err != nil {
+		t.Errorf("request after resetting the channels: %v", err)
+	}
+}
+
+func TestGuildLimits(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminRoles = []string{"admin"}
+	b, _, _ := newTestBot(t, cfg)
+	g := NewGuildHandler(b, cfg, nil)
+	admin := []string{"admin"}
+
+	// guild admins can not raise the amount or shorten the cooldown of the config
+	for _, args := range [][]string{
+		{"set", "amount", "1000000"},
+		{"set", "cooldown", "1ns"},
+	} {
+		if _, err := g.Handle(guildRequest(admin, append([]string{guildCommand}, args...)...)); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	resp, err := g.Handle(guildRequest(nil, myTier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier := resp.Data.(*TierResult); tier.Amount != cfg.TransferAmount || tier.CoolDown != cfg.RequestCoolDown {
+		t.Errorf("guild amount = %v, cooldown = %v, want the config %v, %v", tier.Amount, tier.CoolDown, cfg.TransferAmount, cfg.RequestCoolDown)
+	}
+	req := transferRequest("alice", testAddress)
+	req.GuildID = "g1"
+	resp, err = g.Handle(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount := resp.Data.(*TransferResult).Amount; amount != cfg.TransferAmount {
+		t.Errorf("guild amount = %v, want %v", amount, cfg.TransferAmount)
+	}
+	if _, err := g.Handle(req); err == nil {
+		t.Error("request within the config cooldown accepted")
+	}
+}
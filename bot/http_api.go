@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiPrefix = "/api/v1/"
+
+const httpShutdownTimeout = 5 * time.Second
+
+// HTTPFrontend serves the bot commands as a JSON REST API.
+//
+//	POST /api/v1/fund            {"address": "0x...", "network": "devnet"}
+//	GET  /api/v1/balance/<address>
+//	GET  /api/v1/tx/<tx id>
+//	GET  /api/v1/txs/<address>
+//	GET  /api/v1/status
+//	GET  /api/v1/payouts
+//
+// GET requests select a network with ?net=<name>, fund requests with the network field.
+// With WebEnabled a faucet web page using the API is served on /.
+//
+// Requests carrying a configured key in the X-API-Key header are rate limited
+// per key, all other requests per client IP.
+type HTTPFrontend struct {
+	cfg     BaseConfig
+	handler Handler
+	mux     *http.ServeMux
+	server  *http.Server
+
+	// mu guards apiKeys, which are replaced on a config reload
+	mu      sync.RWMutex
+	apiKeys map[string]bool
+}
+
+func NewHTTPFrontend(cfg BaseConfig) *HTTPFrontend {
+	f := &HTTPFrontend{
+		cfg: cfg,
+		mux: http.NewServeMux(),
+	}
+	f.SetConfig(cfg)
+	f.mux.HandleFunc(apiPrefix+"fund", f.serveFund)
+	f.handleCommand("balance/", balance)
+	f.handleCommand("tx/", txInfo)
+	f.handleCommand("txs/", dumpTxs)
+	f.handleCommand("status", faucetStatus)
+	f.handleCommand("payouts", recentPays)
+	if cfg.WebEnabled {
+		f.mux.HandleFunc("/", f.serveIndex)
+	}
+	return f
+}
+
+func (f *HTTPFrontend) Name() string {
+	return "http"
+}
+
+// Start listens on the configured address and serves requests in the background.
+func (f *HTTPFrontend) Start(h Handler) error {
+	f.handler = h
+	ln, err := net.Listen("tcp", f.cfg.HTTPListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v: %v", f.cfg.HTTPListen, err)
+	}
+	f.server = &http.Server{Handler: f.mux}
+	go func() {
+		if err := f.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("http server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (f *HTTPFrontend) Close() error {
+	if f.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	return f.server.Shutdown(ctx)
+}
+
+type fundRequest struct {
+	Address string `json:"address"`
+	Network string `json:"network"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (f *HTTPFrontend) serveFund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	var body fundRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !strings.HasPrefix(strings.ToLower(body.Address), "0x") {
+		writeError(w, http.StatusBadRequest, "address must be 0x prefixed")
+		return
+	}
+	args := []string{body.Address}
+	if body.Network != "" {
+		args = append(args, netArg, body.Network)
+	}
+	f.handle(w, r, args)
+}
+
+// handleCommand serves command on GET apiPrefix+path, the rest of the url path
+// is passed as the command argument.
+func (f *HTTPFrontend) handleCommand(path string, command string) {
+	path = apiPrefix + path
+	f.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		args := []string{command}
+		if arg := strings.TrimPrefix(r.URL.Path, path); arg != "" {
+			args = append(args, arg)
+		}
+		if name := r.URL.Query().Get("net"); name != "" {
+			args = append(args, netArg, name)
+		}
+		f.handle(w, r, args)
+	})
+}
+
+func (f *HTTPFrontend) handle(w http.ResponseWriter, r *http.Request, args []string) {
+	requesterID, ok := f.requesterID(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	req := &Request{
+		Frontend:      f.Name(),
+		RequesterID:   requesterID,
+		RequesterName: requesterID,
+		Args:          args,
+	}
+
+	resp, err := f.handler.Handle(req)
+	if err != nil {
+		var cd *CoolDownError
+		switch {
+		case errors.As(err, &cd):
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(cd.Retry.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, err.Error())
+		case err == ErrUnknownCommand:
+			writeError(w, http.StatusNotFound, err.Error())
+		case err == ErrShuttingDown, err == ErrDegraded:
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetConfig replaces the api keys.
+func (f *HTTPFrontend) SetConfig(cfg BaseConfig) {
+	keys := make(map[string]bool)
+	for _, key := range cfg.APIKeys {
+		keys[key] = true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apiKeys = keys
+}
+
+// requesterID identifies the caller by API key or by client IP.
+// It returns false if an unknown API key was provided.
+func (f *HTTPFrontend) requesterID(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		f.mu.RLock()
+		known := f.apiKeys[key]
+		f.mu.RUnlock()
+		if !known {
+			return "", false
+		}
+		// never use the key itself as an identifier, it ends up in logs and the audit trail
+		sum := sha256.Sum256([]byte(key))
+		return fmt.Sprintf("key:%x", sum[:8]), true
+	}
+	return "ip:" + f.clientIP(r), true
+}
+
+// clientIP returns the request source address, taken from HTTPForwardedHeader
+// when the API runs behind HTTPProxyHops reverse proxies. The client can send
+// the header itself, so only the entries appended by the proxies are used.
+func (f *HTTPFrontend) clientIP(r *http.Request) string {
+	if f.cfg.HTTPForwardedHeader != "" && f.cfg.HTTPProxyHops > 0 {
+		if fwd := r.Header.Values(f.cfg.HTTPForwardedHeader); len(fwd) > 0 {
+			entries := strings.Split(strings.Join(fwd, ","), ",")
+			i := len(entries) - f.cfg.HTTPProxyHops
+			if i < 0 {
+				i = 0
+			}
+			return strings.TrimSpace(entries[i])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("failed to write http response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		hops   int
+		header string
+		want   string
+	}{
+		{"no header", 1, "", "192.0.2.1"},
+		{"header ignored without hops", 0, "203.0.113.5", "192.0.2.1"},
+		{"one proxy", 1, "203.0.113.5", "203.0.113.5"},
+		{"forged entry", 1, "10.0.0.1, 203.0.113.5", "203.0.113.5"},
+		{"two proxies", 2, "10.0.0.1, 203.0.113.5, 198.51.100.7", "203.0.113.5"},
+		{"fewer entries than hops", 3, "203.0.113.5", "203.0.113.5"},
+	}
+	for _, tc := range tests {
+		cfg := testConfig()
+		cfg.HTTPForwardedHeader = "X-Forwarded-For"
+		cfg.HTTPProxyHops = tc.hops
+		f := NewHTTPFrontend(cfg)
+
+		r := httptest.NewRequest("POST", apiPrefix+"fund", nil)
+		r.RemoteAddr = "192.0.2.1:4321"
+		if tc.header != "" {
+			r.Header.Set("X-Forwarded-For", tc.header)
+		}
+		if ip := f.clientIP(r); ip != tc.want {
+			t.Errorf("%v: client ip = %v, want %v", tc.name, ip, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"strings"
+	"time"
+)
+
+// logger is the logger of the bot package. It discards everything until
+// SetLogger is called, e.g. in tests.
+var logger = zap.NewNop()
+
+// NewLogger returns a logger writing to stderr from level on, one of debug,
+// info, warn or error, as JSON objects or as plain lines.
+func NewLogger(level string, json bool) (*zap.Logger, error) {
+	lvl := zapcore.InfoLevel
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %v", level)
+		}
+	}
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	var enc zapcore.Encoder
+	if json {
+		enc = zapcore.NewJSONEncoder(encCfg)
+	} else {
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		enc = zapcore.NewConsoleEncoder(encCfg)
+	}
+	return zap.New(zapcore.NewCore(enc, zapcore.Lock(os.Stderr), lvl)), nil
+}
+
+// SetLogger sets the logger of the bot package.
+func SetLogger(l *zap.Logger) {
+	logger = l
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Logger returns the logger for everything done for r, tagged with its id.
+func (r *Request) Logger() *zap.Logger {
+	return logger.With(zap.String("request_id", r.ID), zap.String("frontend", r.Frontend))
+}
+
+// requestCommand returns the command name of req for logs and metrics.
+func requestCommand(req *Request) string {
+	if isTransferRequest(req) {
+		return CommandTransfer
+	}
+	return strings.TrimPrefix(req.Args[0], "$")
+}
+
+// LogRequests returns h giving every request an id and logging its outcome.
+// The message content and the reply are only logged with content set, since
+// they may hold personal data.
+func LogRequests(h Handler, content bool) Handler {
+	return &requestLogger{h: h, content: content}
+}
+
+type requestLogger struct {
+	h       Handler
+	content bool
+}
+
+func (l *requestLogger) Handle(req *Request) (*Response, error) {
+	if req.ID == "" {
+		req.ID = newRequestID()
+	}
+	log := req.Logger()
+	var fields []zap.Field
+	if l.content {
+		fields = append(fields, zap.Strings("message", req.Args))
+	}
+
+	start := time.Now()
+	resp, err := l.h.Handle(req)
+	if err == ErrUnknownCommand || len(req.Args) == 0 {
+		// most chat messages are not meant for the bot
+		log.Debug("ignored message", fields...)
+		return resp, err
+	}
+
+	fields = append(fields,
+		zap.String("command", requestCommand(req)),
+		zap.String("requester", req.RequesterID),
+		zap.Duration("duration", time.Since(start)))
+	if err != nil {
+		log.Info("request failed", append(fields, zap.Error(err))...)
+		return resp, err
+	}
+	if resp.Network != "" {
+		fields = append(fields, zap.String("network", resp.Network))
+	}
+	if l.content {
+		fields = append(fields, zap.String("reply", resp.Text))
+	}
+	log.Info("request handled", fields...)
+	return resp, nil
+}
+
+// node returns the backend logging the node calls made for req.
+func (b *botBackend) node(req *Request) Client {
+	log := req.Logger()
+	return &observedClient{Client: b.backend, observe: func(method string, start time.Time, err error) {
+		log.Debug("node call", zap.String("method", method), zap.Duration("duration", time.Since(start)), zap.Error(err))
+	}}
+}
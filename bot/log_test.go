@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger
+	SetLogger(zap.New(core))
+	t.Cleanup(func() { SetLogger(prev) })
+	return logs
+}
+
+func TestLogRequests(t *testing.T) {
+	logs := observeLogs(t)
+	b, _, _ := newTestBot(t, testConfig())
+	h := LogRequests(b, false)
+
+	req := transferRequest("alice", testAddress)
+	if _, err := h.Handle(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ID == "" {
+		t.Fatal("request has no id")
+	}
+
+	handled := logs.FilterMessage("request handled").All()
+	if len(handled) != 1 {
+		t.Fatalf("%v request handled entries, want 1", len(handled))
+	}
+	fields := handled[0].ContextMap()
+	if fields["request_id"] != req.ID || fields["command"] != CommandTransfer {
+		t.Errorf("request handled fields = %v", fields)
+	}
+	if _, ok := fields["message"]; ok {
+		t.Error("message content logged without log-messages")
+	}
+	if _, ok := fields["reply"]; ok {
+		t.Error("reply logged without log-messages")
+	}
+	// the node calls of the request carry its id
+	if n := logs.FilterMessage("node call").FilterField(zap.String("request_id", req.ID)).Len(); n == 0 {
+		t.Error("no node calls logged for the request")
+	}
+}
+
+func TestLogRequestsContent(t *testing.T) {
+	logs := observeLogs(t)
+	b, _, _ := newTestBot(t, testConfig())
+	h := LogRequests(b, true)
+
+	if _, err := h.Handle(command(help)); err != nil {
+		t.Fatal(err)
+	}
+	handled := logs.FilterMessage("request handled").All()
+	if len(handled) != 1 {
+		t.Fatalf("%v request handled entries, want 1", len(handled))
+	}
+	fields := handled[0].ContextMap()
+	if _, ok := fields["message"]; !ok {
+		t.Error("message content not logged")
+	}
+	if fields["reply"] == "" {
+		t.Error("reply not logged")
+	}
+
+	if _, err := h.Handle(command("hello")); err != ErrUnknownCommand {
+		t.Fatal(err)
+	}
+	if logs.FilterMessage("ignored message").Len() != 1 {
+		t.Error("unknown command not logged as ignored")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	if _, err := NewLogger("debug", true); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewLogger("loud", false); err == nil {
+		t.Error("invalid level accepted")
+	}
+}
@@ -0,0 +1,256 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const metricsNamespace = "tapbot"
+
+// request outcomes
+const (
+	outcomeOK       = "ok"
+	outcomeCoolDown = "cooldown"
+	outcomeError    = "error"
+)
+
+// Metrics collects the faucet metrics and serves them on /metrics for
+// Prometheus. A nil Metrics collects nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+	server   *http.Server
+
+	requests      *prometheus.CounterVec
+	coolDowns     *prometheus.CounterVec
+	payouts       *prometheus.CounterVec
+	smidgeSent    *prometheus.CounterVec
+	nodeCalls     *prometheus.HistogramVec
+	nodeErrors    *prometheus.CounterVec
+	networkStates *networkCollector
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Requests handled by command and outcome.",
+		}, []string{"network", "command", "outcome"}),
+		coolDowns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cooldown_denials_total",
+			Help:      "Fund requests denied by a cooldown or daily cap.",
+		}, []string{"network"}),
+		payouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "payouts_total",
+			Help:      "Payouts submitted to the node.",
+		}, []string{"network"}),
+		smidgeSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "smidge_sent_total",
+			Help:      "Smidge paid out.",
+		}, []string{"network"}),
+		nodeCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_call_duration_seconds",
+			Help:      "Latency of node calls by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"network", "method"}),
+		nodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_call_errors_total",
+			Help:      "Failed node calls by method.",
+		}, []string{"network", "method"}),
+		networkStates: newNetworkCollector(),
+	}
+	m.registry.MustRegister(m.requests, m.coolDowns, m.payouts, m.smidgeSent, m.nodeCalls, m.nodeErrors, m.networkStates)
+	return m
+}
+
+// Start serves /metrics on addr in the background.
+func (m *Metrics) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Handler: mux}
+	go func() {
+		if err := m.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (m *Metrics) Close() error {
+	if m == nil || m.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	return m.server.Shutdown(ctx)
+}
+
+// Handler returns h counting the requests of network.
+func (m *Metrics) Handler(network string, h Handler) Handler {
+	if m == nil {
+		return h
+	}
+	return &metricsHandler{m: m, network: network, h: h}
+}
+
+// Client returns c measuring the calls to the node of network.
+func (m *Metrics) Client(network string, c Client) Client {
+	if m == nil {
+		return c
+	}
+	return &observedClient{Client: c, observe: func(method string, start time.Time, err error) {
+		m.nodeCalls.WithLabelValues(network, method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.nodeErrors.WithLabelValues(network, method).Inc()
+		}
+	}}
+}
+
+// AddNetwork exports the balance of wallets, the payouts tracked by tracker
+// and the node status of network, read from c whenever the metrics are scraped.
+func (m *Metrics) AddNetwork(network string, c Client, wallets []*Wallet, tracker *TxTracker) {
+	if m == nil {
+		return
+	}
+	m.networkStates.add(&networkSource{network: network, client: c, wallets: wallets, tracker: tracker})
+}
+
+type metricsHandler struct {
+	m       *Metrics
+	network string
+	h       Handler
+}
+
+func (h *metricsHandler) Handle(req *Request) (*Response, error) {
+	resp, err := h.h.Handle(req)
+	if err == ErrUnknownCommand || len(req.Args) == 0 {
+		return resp, err
+	}
+	command := requestCommand(req)
+
+	outcome := outcomeOK
+	var cdErr *CoolDownError
+	switch {
+	case errors.As(err, &cdErr):
+		outcome = outcomeCoolDown
+		h.m.coolDowns.WithLabelValues(h.network).Inc()
+	case err != nil:
+		outcome = outcomeError
+	}
+	h.m.requests.WithLabelValues(h.network, command, outcome).Inc()
+
+	if err != nil {
+		return resp, err
+	}
+	if res, ok := resp.Data.(*TransferResult); ok && !res.DryRun {
+		h.m.payouts.WithLabelValues(h.network).Inc()
+		h.m.smidgeSent.WithLabelValues(h.network).Add(float64(res.Amount))
+	}
+	return resp, nil
+}
+
+// networkSource is where the state of a network is read from on a scrape.
+type networkSource struct {
+	network string
+	client  Client
+	wallets []*Wallet
+	tracker *TxTracker
+}
+
+// networkCollector reads the balances, tracked payouts and node status of the
+// networks when scraped, so they are never stale.
+type networkCollector struct {
+	balance       *prometheus.Desc
+	walletBalance *prometheus.Desc
+	pending       *prometheus.Desc
+	synced        *prometheus.Desc
+	topLayer      *prometheus.Desc
+
+	mu      sync.Mutex
+	sources []*networkSource
+}
+
+func newNetworkCollector() *networkCollector {
+	name := func(n string) string {
+		return prometheus.BuildFQName(metricsNamespace, "", n)
+	}
+	return &networkCollector{
+		balance:       prometheus.NewDesc(name("faucet_balance_smidge"), "Balance of all faucet wallets.", []string{"network"}, nil),
+		walletBalance: prometheus.NewDesc(name("wallet_balance_smidge"), "Balance of a faucet wallet.", []string{"network", "wallet"}, nil),
+		pending:       prometheus.NewDesc(name("pending_txs"), "Payouts tracked until they are final.", []string{"network"}, nil),
+		synced:        prometheus.NewDesc(name("node_synced"), "1 if the node is synced.", []string{"network"}, nil),
+		topLayer:      prometheus.NewDesc(name("node_top_layer"), "Top layer of the node.", []string{"network"}, nil),
+	}
+}
+
+func (c *networkCollector) add(s *networkSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, s)
+}
+
+func (c *networkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.balance
+	ch <- c.walletBalance
+	ch <- c.pending
+	ch <- c.synced
+	ch <- c.topLayer
+}
+
+func (c *networkCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	sources := append([]*networkSource{}, c.sources...)
+	c.mu.Unlock()
+	for _, s := range sources {
+		if s.tracker != nil {
+			ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(len(s.tracker.Pending())), s.network)
+		}
+
+		status, err := s.client.NodeStatus()
+		synced := 0.0
+		if err == nil && status.IsSynced {
+			synced = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.synced, prometheus.GaugeValue, synced, s.network)
+		if err != nil {
+			// the balances can not be read either
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.topLayer, prometheus.GaugeValue, float64(status.TopLayer.GetNumber()), s.network)
+
+		var total uint64
+		complete := true
+		for _, w := range s.wallets {
+			account, err := s.client.AccountState(w.Address)
+			if err != nil {
+				complete = false
+				continue
+			}
+			balance := account.StateCurrent.GetBalance().GetValue()
+			total += balance
+			ch <- prometheus.MustNewConstMetric(c.walletBalance, prometheus.GaugeValue, float64(balance), s.network, w.Address.String())
+		}
+		// a partial total would look like a drop in balance
+		if complete {
+			ch <- prometheus.MustNewConstMetric(c.balance, prometheus.GaugeValue, float64(total), s.network)
+		}
+	}
+}
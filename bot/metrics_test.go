@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRequests(t *testing.T) {
+	m := NewMetrics()
+	b, client, _ := newTestBot(t, testConfig())
+	b.backend = m.Client("testnet", client)
+	h := m.Handler("testnet", b)
+
+	if _, err := h.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Handle(transferRequest("alice", testAddress)); err == nil {
+		t.Fatal("no cooldown error")
+	}
+	if _, err := h.Handle(command(help)); err != nil {
+		t.Fatal(err)
+	}
+	// unknown commands are not counted
+	if _, err := h.Handle(command("hello")); err != ErrUnknownCommand {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		command, outcome string
+		want             float64
+	}{
+		{CommandTransfer, outcomeOK, 1},
+		{CommandTransfer, outcomeCoolDown, 1},
+		{"help", outcomeOK, 1},
+		{"hello", outcomeError, 0},
+	} {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues("testnet", c.command, c.outcome)); got != c.want {
+			t.Errorf("%v %v requests = %v, want %v", c.command, c.outcome, got, c.want)
+		}
+	}
+	if got := testutil.ToFloat64(m.coolDowns.WithLabelValues("testnet")); got != 1 {
+		t.Errorf("cooldown denials = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.payouts.WithLabelValues("testnet")); got != 1 {
+		t.Errorf("payouts = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.smidgeSent.WithLabelValues("testnet")); got != 100 {
+		t.Errorf("smidge sent = %v, want 100", got)
+	}
+
+	client.mu.Lock()
+	client.transferErr = errors.New("connection refused")
+	client.mu.Unlock()
+	if _, err := h.Handle(transferRequest("bob", otherTestAddress)); err == nil {
+		t.Fatal("no transfer error")
+	}
+	if got := testutil.ToFloat64(m.nodeErrors.WithLabelValues("testnet", "Transfer")); got != 1 {
+		t.Errorf("transfer errors = %v, want 1", got)
+	}
+}
+
+func TestMetricsNetworkState(t *testing.T) {
+	m := NewMetrics()
+	w := testWallet(1)
+	client := newFakeClient()
+	client.setAccount(w.Address, 5000, 0, 5000, 0)
+	tracker, _ := newTestTracker(client)
+	defer tracker.Close()
+	m.AddNetwork("testnet", client, []*Wallet{w}, tracker)
+
+	want := `
+# HELP tapbot_faucet_balance_smidge Balance of all faucet wallets.
+# TYPE tapbot_faucet_balance_smidge gauge
+tapbot_faucet_balance_smidge{network="testnet"} 5000
+# HELP tapbot_node_synced 1 if the node is synced.
+# TYPE tapbot_node_synced gauge
+tapbot_node_synced{network="testnet"} 1
+# HELP tapbot_node_top_layer Top layer of the node.
+# TYPE tapbot_node_top_layer gauge
+tapbot_node_top_layer{network="testnet"} 10
+# HELP tapbot_pending_txs Payouts tracked until they are final.
+# TYPE tapbot_pending_txs gauge
+tapbot_pending_txs{network="testnet"} 0
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(want),
+		"tapbot_faucet_balance_smidge", "tapbot_node_synced", "tapbot_node_top_layer", "tapbot_pending_txs"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	b, client, _ := newTestBot(t, testConfig())
+	if m.Handler("testnet", b) != Handler(b) || m.Client("testnet", client) != Client(client) {
+		t.Error("nil metrics wrapped the handler or client")
+	}
+	m.AddNetwork("testnet", client, nil, nil)
+	if err := m.Close(); err != nil {
+		t.Error(err)
+	}
+}
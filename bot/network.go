@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultNetworkName = "default"
+
+const (
+	netArg   = "--net"
+	networks = "$networks"
+)
+
+// NetworkConfig configures an additional network served by the bot.
+// Fields left empty fall back to the top level config.
+type NetworkConfig struct {
+	Name string `mapstructure:"name"`
+	// Channels are the channel IDs bound to the network
+	Channels         []string      `mapstructure:"channels"`
+	Server           string        `mapstructure:"server"`
+	Servers          []string      `mapstructure:"servers"`
+	SecureConnection bool          `mapstructure:"secure"`
+	Mnemonic         string        `mapstructure:"mnemonic"`
+	WalletCount      int           `mapstructure:"wallet-count"`
+	PublicKey        string        `mapstructure:"pub-key"`
+	PrivateKey       string        `mapstructure:"priv-key"`
+	TransferAmount   uint64        `mapstructure:"transfer-amount"`
+	RequestCoolDown  time.Duration `mapstructure:"cooldown"`
+	// the reserve wallet is never inherited from the top level config
+	ReserveMnemonic   string `mapstructure:"reserve-mnemonic"`
+	ReservePrivateKey string `mapstructure:"reserve-priv-key"`
+}
+
+// NetworkConfigs returns the config of every network served by the bot. The top
+// level config is the default network, named by Network, followed by Networks.
+func (c BaseConfig) NetworkConfigs() []BaseConfig {
+	base := c
+	base.Networks = nil
+	if base.Network == "" {
+		base.Network = defaultNetworkName
+	}
+	configs := []BaseConfig{base}
+	for _, n := range c.Networks {
+		configs = append(configs, base.withNetwork(n))
+	}
+	return configs
+}
+
+// withNetwork returns c with the fields set in n applied.
+func (c BaseConfig) withNetwork(n NetworkConfig) BaseConfig {
+	c.Network = n.Name
+	c.NetworkChannels = n.Channels
+	if n.Server != "" || len(n.Servers) > 0 {
+		c.Server, c.Servers, c.SecureConnection = n.Server, n.Servers, n.SecureConnection
+	}
+	if n.Mnemonic != "" || n.PrivateKey != "" {
+		c.Mnemonic, c.PrivateKey, c.PublicKey, c.WalletCount = n.Mnemonic, n.PrivateKey, n.PublicKey, n.WalletCount
+	}
+	c.ReserveMnemonic, c.ReservePrivateKey = n.ReserveMnemonic, n.ReservePrivateKey
+	if n.TransferAmount != 0 {
+		c.TransferAmount = n.TransferAmount
+	}
+	if n.RequestCoolDown != 0 {
+		c.RequestCoolDown = n.RequestCoolDown
+	}
+	return c
+}
+
+// NetworkRouter is a Handler serving several networks. A request goes to the
+// network named by a "--net <name>" argument, else to the network bound to its
+// channel, else to the first network added.
+type NetworkRouter struct {
+	handlers map[string]Handler
+	first    string
+
+	// mu guards channels, which are rebound on a config reload
+	mu       sync.RWMutex
+	channels map[string]string
+}
+
+func NewNetworkRouter() *NetworkRouter {
+	return &NetworkRouter{
+		handlers: make(map[string]Handler),
+		channels: make(map[string]string),
+	}
+}
+
+// Add registers the handler of network name, bound to channels.
+func (r *NetworkRouter) Add(name string, h Handler, channels []string) {
+	if r.first == "" {
+		r.first = name
+	}
+	r.handlers[name] = h
+	r.SetChannels(name, channels)
+}
+
+// SetChannels binds network name to channels instead of its current channels.
+func (r *NetworkRouter) SetChannels(name string, channels []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ch, n := range r.channels {
+		if n == name {
+			delete(r.channels, ch)
+		}
+	}
+	for _, ch := range channels {
+		r.channels[ch] = name
+	}
+}
+
+func (r *NetworkRouter) Handle(req *Request) (*Response, error) {
+	args, name := splitNetArg(req.Args)
+	if len(args) > 0 && args[0] == networks {
+		return r.listNetworks(), nil
+	}
+	if name == "" {
+		r.mu.RLock()
+		name = r.channels[req.ChannelID]
+		r.mu.RUnlock()
+	}
+	if name == "" {
+		name = r.first
+	}
+	h, ok := r.handlers[name]
+	if !ok {
+		if len(args) == 0 {
+			return nil, ErrUnknownCommand
+		}
+		return nil, fmt.Errorf("unknown network %v, available networks: %v", name, strings.Join(r.names(), ", "))
+	}
+
+	routed := *req
+	routed.Args = args
+	resp, err := h.Handle(&routed)
+	if resp != nil {
+		resp.Network = name
+	}
+	return resp, err
+}
+
+func (r *NetworkRouter) names() []string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *NetworkRouter) listNetworks() *Response {
+	names := r.names()
+	return &Response{
+		Command: networks,
+		Text:    fmt.Sprintf("networks: %v\ndefault: %v\nuse '--net <name>' to select a network", strings.Join(names, ", "), r.first),
+		Data:    &NetworksResult{Networks: names, Default: r.first},
+	}
+}
+
+// splitNetArg removes "--net <name>" or "--net=<name>" from args and returns the name.
+func splitNetArg(args []string) ([]string, string) {
+	rest := make([]string, 0, len(args))
+	name := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == netArg && i+1 < len(args):
+			name = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], netArg+"="):
+			name = strings.TrimPrefix(args[i], netArg+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, name
+}
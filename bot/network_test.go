@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouterPrecedence(t *testing.T) {
+	r := NewNetworkRouter()
+	devnet, _, _ := newTestBot(t, testConfig())
+	testnet, _, _ := newTestBot(t, testConfig())
+	devnetH, testnetH := &recordingHandler{h: devnet}, &recordingHandler{h: testnet}
+	r.Add("devnet", devnetH, nil)
+	r.Add("testnet", testnetH, []string{"c1"})
+	handlers := map[string]*recordingHandler{"devnet": devnetH, "testnet": testnetH}
+
+	tests := []struct {
+		name    string
+		channel string
+		args    []string
+		network string
+		// args are the args passed to the network, without the net argument
+		routed []string
+		err    string
+	}{
+		{"default network", "c9", []string{help}, "devnet", []string{help}, ""},
+		{"channel binding", "c1", []string{help}, "testnet", []string{help}, ""},
+		{"net arg over channel", "c1", []string{balance, netArg, "devnet", testAddress}, "devnet", []string{balance, testAddress}, ""},
+		{"net arg over default", "c9", []string{testAddress, netArg + "=testnet"}, "testnet", []string{testAddress}, ""},
+		{"last net arg wins", "", []string{help, netArg, "testnet", netArg, "devnet"}, "devnet", []string{help}, ""},
+		{"unknown network", "c1", []string{help, netArg, "mainnet"}, "", nil, "unknown network mainnet, available networks: devnet, testnet"},
+		{"net arg only", "", []string{netArg, "mainnet"}, "", nil, ErrUnknownCommand.Error()},
+	}
+	for _, tc := range tests {
+		req := command(tc.args...)
+		req.ChannelID = tc.channel
+		resp, err := r.Handle(req)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%v: error = %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		if resp.Network != tc.network {
+			t.Errorf("%v: routed to %v, want %v", tc.name, resp.Network, tc.network)
+		}
+		if args := handlers[tc.network].last().Args; !reflect.DeepEqual(args, tc.routed) {
+			t.Errorf("%v: args = %q, want %q", tc.name, args, tc.routed)
+		}
+	}
+
+	// networks lists the networks on any network
+	resp, err := r.Handle(command(networks, netArg, "mainnet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := resp.Data.(*NetworksResult); res.Default != "devnet" || len(res.Networks) != 2 {
+		t.Errorf("networks = %+v", res)
+	}
+}
@@ -0,0 +1,26 @@
+package bot
+
+import "go.uber.org/zap"
+
+// Notifier delivers operator notifications outside of the request/reply flow.
+type Notifier interface {
+	NotifyChannel(channelID string, msg string) error
+	NotifyUser(userID string, msg string) error
+}
+
+// NotifyOperators sends msg to the alert channel and every user in the alert list.
+func NotifyOperators(n Notifier, cfg BaseConfig, msg string) {
+	if n == nil {
+		return
+	}
+	if cfg.AlertChannel != "" {
+		if err := n.NotifyChannel(cfg.AlertChannel, msg); err != nil {
+			logger.Warn("failed to send alert to channel", zap.String("channel", cfg.AlertChannel), zap.Error(err))
+		}
+	}
+	for _, user := range cfg.AlertUsers {
+		if err := n.NotifyUser(user, msg); err != nil {
+			logger.Warn("failed to send alert to user", zap.String("user", user), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"time"
+)
+
+// observedClient is a Client reporting every call of a method started at start
+// to observe, for metrics and logs.
+type observedClient struct {
+	Client
+	observe func(method string, start time.Time, err error)
+}
+
+func (c *observedClient) NodeStatus() (*apitypes.NodeStatus, error) {
+	start := time.Now()
+	res, err := c.Client.NodeStatus()
+	c.observe("NodeStatus", start, err)
+	return res, err
+}
+
+func (c *observedClient) AccountState(address gosmtypes.Address) (*apitypes.Account, error) {
+	start := time.Now()
+	res, err := c.Client.AccountState(address)
+	c.observe("AccountState", start, err)
+	return res, err
+}
+
+func (c *observedClient) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error) {
+	start := time.Now()
+	res, err := c.Client.Transfer(recipient, nonce, amount, gasPrice, gasLimit, key)
+	c.observe("Transfer", start, err)
+	return res, err
+}
+
+func (c *observedClient) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	start := time.Now()
+	state, tx, err := c.Client.TransactionState(txId, includeTx)
+	c.observe("TransactionState", start, err)
+	return state, tx, err
+}
+
+func (c *observedClient) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error) {
+	start := time.Now()
+	txs, total, err := c.Client.GetMeshTransactions(address, offset, maxResults)
+	c.observe("GetMeshTransactions", start, err)
+	return txs, total, err
+}
+
+func (c *observedClient) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	start := time.Now()
+	res, err := c.Client.SubscribeTransactionState(ctx, txId)
+	c.observe("SubscribeTransactionState", start, err)
+	return res, err
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+const maxRecentPayouts = 20
+
+// addRecentPayout records a payout shown by $recent_payouts, newest first.
+// Must be called with b.mu held.
+func (b *botBackend) addRecentPayout(p TransferResult) {
+	b.recent = append([]TransferResult{p}, b.recent...)
+	if len(b.recent) > maxRecentPayouts {
+		b.recent = b.recent[:maxRecentPayouts]
+	}
+}
+
+// SetTracker tracks the payouts of the bot with t.
+func (b *botBackend) SetTracker(t *TxTracker) {
+	b.tracker = t
+	t.AddListener(b)
+}
+
+// TxUpdated records the state of a tracked payout in the recent payouts, the
+// payout counters and, once it is final, the audit log.
+func (b *botBackend) TxUpdated(u *TxUpdate) {
+	b.mu.Lock()
+	for i := range b.recent {
+		if b.recent[i].TxID == u.TxID {
+			b.recent[i].State = u.StateText()
+		}
+	}
+	if u.Final {
+		if u.Confirmed() {
+			b.confirmed++
+		} else {
+			b.failed++
+		}
+	}
+	b.mu.Unlock()
+
+	if !u.Final {
+		return
+	}
+	entry := &AuditEntry{
+		Network: u.Network,
+		Address: u.Payout.Address,
+		Amount:  u.Payout.Amount,
+		TxID:    u.TxID,
+		State:   u.StateText(),
+	}
+	if u.Request != nil {
+		entry.Requester, entry.RequesterName = u.Request.RequesterID, u.Request.RequesterName
+	}
+	entry.Decision = AuditFailed
+	if u.Confirmed() {
+		entry.Decision = AuditConfirmed
+	}
+	if err := b.auditLog.Append(entry); err != nil {
+		logger.Error("failed to write audit log", zap.Error(err))
+	}
+}
+
+func (b *botBackend) getRecentPayouts(req *Request) (*Response, error) {
+	b.mu.Lock()
+	res := &RecentPayoutsResult{Payouts: append([]TransferResult{}, b.recent...)}
+	b.mu.Unlock()
+
+	msg := "no payouts yet"
+	if len(res.Payouts) > 0 {
+		msg = "recent payouts:\n"
+		for _, p := range res.Payouts {
+			msg += fmt.Sprintf("%v %v to %v txID: %v\n", p.Time.Format(time.RFC3339), p.Amount, p.Address, p.TxID)
+		}
+	}
+	return &Response{Command: recentPays, Text: msg, Data: res}, nil
+}
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultNodeCheckInterval = 10 * time.Second
+
+type poolNode struct {
+	server  string
+	client  Client
+	healthy bool
+	synced  bool
+	peers   uint64
+	layer   uint32
+	latency time.Duration
+}
+
+// NodePool is a Client backed by several nodes. It tracks the health, sync state,
+// peers and latency of every node, routes reads to healthy nodes and submits
+// transactions to the best synced node, failing over to the next node when a
+// node can not be reached.
+type NodePool struct {
+	mu       sync.RWMutex
+	nodes    []*poolNode
+	interval time.Duration
+}
+
+// NewNodePool connects to servers with dial and runs a first health check.
+func NewNodePool(servers []string, interval time.Duration, dial func(server string) (Client, error)) (*NodePool, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no servers configured")
+	}
+	if interval <= 0 {
+		interval = defaultNodeCheckInterval
+	}
+	p := &NodePool{interval: interval}
+	for _, server := range servers {
+		c, err := dial(server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %v: %v", server, err)
+		}
+		p.nodes = append(p.nodes, &poolNode{server: server, client: c})
+	}
+	p.checkAll()
+	return p, nil
+}
+
+// Close closes the connections of all nodes.
+func (p *NodePool) Close() error {
+	for _, n := range p.nodes {
+		closeClient(n.client)
+	}
+	return nil
+}
+
+// Run checks the health of all nodes periodically until stop is closed.
+func (p *NodePool) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+func (p *NodePool) checkAll() {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *poolNode) {
+			defer wg.Done()
+			p.check(n)
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (p *NodePool) check(n *poolNode) {
+	start := time.Now()
+	st, err := n.client.NodeStatus()
+	latency := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wasHealthy, wasSynced := n.healthy, n.synced
+	if err != nil {
+		n.healthy, n.synced = false, false
+		if wasHealthy {
+			logger.Warn("node pool: node is down", zap.String("server", n.server), zap.Error(err))
+		}
+		return
+	}
+	n.healthy = true
+	n.synced = st.IsSynced
+	n.peers = st.ConnectedPeers
+	n.layer = st.TopLayer.GetNumber()
+	n.latency = latency
+	if !wasHealthy || wasSynced != n.synced {
+		logger.Info("node pool: node is up", zap.String("server", n.server), zap.Bool("synced", n.synced))
+	}
+}
+
+// markDown flags a node unreachable until the next successful health check.
+func (p *NodePool) markDown(n *poolNode, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n.healthy {
+		logger.Warn("node pool: node failed", zap.String("server", n.server), zap.Error(err))
+	}
+	n.healthy, n.synced = false, false
+}
+
+// readNodes returns healthy nodes, synced ones first, by latency.
+func (p *NodePool) readNodes() []*poolNode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var nodes []*poolNode
+	for _, n := range p.nodes {
+		if n.healthy {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].synced != nodes[j].synced {
+			return nodes[i].synced
+		}
+		return nodes[i].latency < nodes[j].latency
+	})
+	return nodes
+}
+
+// submitNodes returns synced nodes, best first by top layer, peers and latency.
+func (p *NodePool) submitNodes() []*poolNode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var nodes []*poolNode
+	for _, n := range p.nodes {
+		if n.healthy && n.synced {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.layer != b.layer {
+			return a.layer > b.layer
+		}
+		if a.peers != b.peers {
+			return a.peers > b.peers
+		}
+		return a.latency < b.latency
+	})
+	return nodes
+}
+
+// isConnectionError returns true for errors caused by an unreachable node, as opposed
+// to errors returned by a working node, e.g. for an unknown transaction.
+func isConnectionError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return true
+	}
+	return false
+}
+
+// try calls fn on nodes in order until one succeeds or fails with a non connection error.
+func (p *NodePool) try(nodes []*poolNode, fn func(c Client) error) error {
+	if len(nodes) == 0 {
+		return fmt.Errorf("no healthy node available")
+	}
+	var err error
+	for _, n := range nodes {
+		err = fn(n.client)
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+		p.markDown(n, err)
+	}
+	return err
+}
+
+// NodeStatus returns the status of the best available node.
+func (p *NodePool) NodeStatus() (*apitypes.NodeStatus, error) {
+	var res *apitypes.NodeStatus
+	nodes := p.submitNodes()
+	if len(nodes) == 0 {
+		nodes = p.readNodes()
+	}
+	err := p.try(nodes, func(c Client) (err error) {
+		res, err = c.NodeStatus()
+		return err
+	})
+	return res, err
+}
+
+func (p *NodePool) AccountState(address gosmtypes.Address) (*apitypes.Account, error) {
+	var res *apitypes.Account
+	err := p.try(p.readNodes(), func(c Client) (err error) {
+		res, err = c.AccountState(address)
+		return err
+	})
+	return res, err
+}
+
+// Transfer submits to the best synced node. Failing over with the same nonce is
+// safe since the same transaction is submitted again.
+func (p *NodePool) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error) {
+	var res *apitypes.TransactionState
+	err := p.try(p.submitNodes(), func(c Client) (err error) {
+		res, err = c.Transfer(recipient, nonce, amount, gasPrice, gasLimit, key)
+		return err
+	})
+	return res, err
+}
+
+func (p *NodePool) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	var state *apitypes.TransactionState
+	var tx *apitypes.Transaction
+	err := p.try(p.readNodes(), func(c Client) (err error) {
+		state, tx, err = c.TransactionState(txId, includeTx)
+		return err
+	})
+	return state, tx, err
+}
+
+func (p *NodePool) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error) {
+	var txs []*apitypes.MeshTransaction
+	var total uint32
+	err := p.try(p.readNodes(), func(c Client) (err error) {
+		txs, total, err = c.GetMeshTransactions(address, offset, maxResults)
+		return err
+	})
+	return txs, total, err
+}
+
+// SubscribeTransactionState subscribes on the first node serving the stream.
+func (p *NodePool) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	var res <-chan *apitypes.TransactionState
+	err := p.try(p.readNodes(), func(c Client) (err error) {
+		res, err = c.SubscribeTransactionState(ctx, txId)
+		return err
+	})
+	return res, err
+}
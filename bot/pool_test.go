@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestPool returns a pool of the fake nodes, by server name.
+func newTestPool(t *testing.T, nodes map[string]*fakeClient, servers ...string) *NodePool {
+	t.Helper()
+	p, err := NewNodePool(servers, time.Hour, func(server string) (Client, error) {
+		c, ok := nodes[server]
+		if !ok {
+			return nil, fmt.Errorf("unknown server %v", server)
+		}
+		return c, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// fakeNode returns a node with the given sync state, top layer and peers.
+func fakeNode(synced bool, layer uint32, peers uint64) *fakeClient {
+	c := newFakeClient()
+	c.status.IsSynced = synced
+	c.status.TopLayer.Number = layer
+	c.status.ConnectedPeers = peers
+	return c
+}
+
+// poolServers returns the servers of nodes separated by spaces.
+func poolServers(nodes []*poolNode) string {
+	var servers []string
+	for _, n := range nodes {
+		servers = append(servers, n.server)
+	}
+	return strings.Join(servers, " ")
+}
+
+func TestNodePoolSubmitOrder(t *testing.T) {
+	nodes := map[string]*fakeClient{
+		"behind":  fakeNode(true, 10, 5),
+		"best":    fakeNode(true, 12, 2),
+		"peers":   fakeNode(true, 10, 8),
+		"syncing": fakeNode(false, 20, 9),
+		"offline": fakeNode(true, 30, 9),
+	}
+	nodes["offline"].setDown(errUnavailable)
+	p := newTestPool(t, nodes, "behind", "syncing", "offline", "peers", "best")
+
+	// synced nodes only, by top layer then peers
+	if got := poolServers(p.submitNodes()); got != "best peers behind" {
+		t.Errorf("submit nodes = %v, want best peers behind", got)
+	}
+	// reads go to every healthy node, synced ones first
+	if got := p.readNodes(); len(got) != 4 || got[3].server != "syncing" {
+		t.Errorf("read nodes = %v, want the syncing node last", poolServers(got))
+	}
+
+	if _, err := p.Transfer(testWallet(2).Address, 0, 100, 1, 100, testWallet(1).Key); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes["best"].sent()) != 1 {
+		t.Error("transfer not submitted to the best synced node")
+	}
+	if len(nodes["syncing"].sent())+len(nodes["offline"].sent()) != 0 {
+		t.Error("transfer submitted to a node which is not synced or down")
+	}
+}
+
+func TestNodePoolFailover(t *testing.T) {
+	nodes := map[string]*fakeClient{
+		"a": fakeNode(true, 12, 5),
+		"b": fakeNode(true, 10, 5),
+	}
+	p := newTestPool(t, nodes, "a", "b")
+
+	// a connection error fails over to the next node and marks the node down
+	nodes["a"].transferErr = errUnavailable
+	if _, err := p.Transfer(testWallet(2).Address, 0, 100, 1, 100, testWallet(1).Key); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes["b"].sent()) != 1 {
+		t.Fatal("transfer not failed over to b")
+	}
+	if got := poolServers(p.readNodes()); got != "b" {
+		t.Errorf("healthy nodes = %v, want b", got)
+	}
+	nodes["a"].transferErr = nil
+	if _, err := p.Transfer(testWallet(2).Address, 1, 100, 1, 100, testWallet(1).Key); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes["a"].sent()) != 0 || len(nodes["b"].sent()) != 2 {
+		t.Error("node marked down was used before a health check")
+	}
+
+	// the health check brings the node back
+	p.checkAll()
+	if got := poolServers(p.submitNodes()); got != "a b" {
+		t.Errorf("submit nodes after the health check = %v, want a b", got)
+	}
+
+	// errors of a working node are returned without failing over
+	nodes["a"].transferErr = status.Error(codes.InvalidArgument, "bad nonce")
+	if _, err := p.Transfer(testWallet(2).Address, 2, 100, 1, 100, testWallet(1).Key); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("error = %v, want the node error", err)
+	}
+	if len(nodes["b"].sent()) != 2 || len(p.submitNodes()) != 2 {
+		t.Error("node error failed over or marked the node down")
+	}
+
+	// with every node down the pool fails
+	nodes["a"].transferErr = errUnavailable
+	nodes["b"].transferErr = errUnavailable
+	if _, err := p.Transfer(testWallet(2).Address, 2, 100, 1, 100, testWallet(1).Key); !isConnectionError(err) {
+		t.Errorf("error = %v, want the connection error", err)
+	}
+	if _, err := p.Transfer(testWallet(2).Address, 2, 100, 1, 100, testWallet(1).Key); err == nil || err.Error() != "no healthy node available" {
+		t.Errorf("error = %v, want no healthy node", err)
+	}
+}
+
+func TestNodePoolRecovery(t *testing.T) {
+	nodes := map[string]*fakeClient{"a": fakeNode(true, 10, 5)}
+	nodes["a"].setDown(errUnavailable)
+	p, err := NewNodePool([]string{"a"}, 10*time.Millisecond, func(server string) (Client, error) {
+		return nodes[server], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AccountState(testWallet(1).Address); err == nil {
+		t.Fatal("read served by a node which is down")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go p.Run(stop)
+	nodes["a"].setDown(nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.readNodes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("node not recovered by the health check")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := p.AccountState(testWallet(1).Address); err != nil {
+		t.Error(err)
+	}
+}
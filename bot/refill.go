@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"fmt"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const defaultRefillCheckInterval = time.Minute
+
+const refillGas = uint64(50)
+
+// Refiller tops up the faucet hot wallets from a reserve wallet when their
+// balance drops below a threshold. The reserve wallet is only known to the
+// refiller and never reachable from the request handlers.
+type Refiller struct {
+	backend  Client
+	reserve  *Wallet
+	wallets  []*Wallet
+	notifier Notifier
+	cfg      BaseConfig
+	auditLog *AuditLog
+
+	mu      sync.Mutex
+	history []payout
+	// limitAlerted is set once operators were told about the daily limit
+	limitAlerted bool
+}
+
+func NewRefiller(backend Client, reserve *Wallet, wallets []*Wallet, notifier Notifier, cfg BaseConfig) *Refiller {
+	return &Refiller{
+		backend:  backend,
+		reserve:  reserve,
+		wallets:  wallets,
+		notifier: notifier,
+		cfg:      cfg,
+	}
+}
+
+// SetAuditLog records refills to l.
+func (r *Refiller) SetAuditLog(l *AuditLog) {
+	r.auditLog = l
+}
+
+// Refills returns the refills of the last 24 hours counted against the daily max.
+func (r *Refiller) Refills() []PayoutRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []PayoutRecord
+	for _, p := range r.history {
+		if time.Since(p.at) < dailyCapWindow {
+			records = append(records, PayoutRecord{Time: p.at, Amount: p.amount})
+		}
+	}
+	return records
+}
+
+// Restore counts the refills returned by Refills against the daily max.
+func (r *Refiller) Restore(records []PayoutRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range records {
+		r.history = append(r.history, payout{at: rec.Time, amount: rec.Amount})
+	}
+}
+
+// Enabled returns true if a reserve wallet and refill amounts are configured.
+// Nothing is refilled in a dry run.
+func (r *Refiller) Enabled() bool {
+	return r.reserve != nil && r.cfg.RefillThreshold > 0 && r.cfg.RefillAmount > 0 && !r.cfg.DryRun
+}
+
+// Run checks the hot wallets periodically until stop is closed.
+func (r *Refiller) Run(stop <-chan struct{}) {
+	interval := r.cfg.RefillCheckInterval
+	if interval <= 0 {
+		interval = defaultRefillCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	r.check()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+func (r *Refiller) check() {
+	for _, w := range r.wallets {
+		account, err := r.backend.AccountState(w.Address)
+		if err != nil {
+			logger.Warn("refill: failed to read hot wallet", zap.String("wallet", w.Address.String()), zap.Error(err))
+			continue
+		}
+		// the projected balance includes refills that were sent but not applied yet
+		balance := account.StateProjected.GetBalance().GetValue()
+		if balance >= r.cfg.RefillThreshold {
+			continue
+		}
+		if err := r.refill(w, balance); err != nil {
+			logger.Warn("refill failed", zap.String("wallet", w.Address.String()), zap.Error(err))
+		}
+	}
+}
+
+// refilledToday returns the amount sent from the reserve in the last 24 hours.
+func (r *Refiller) refilledToday(now time.Time) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total uint64
+	recent := r.history[:0]
+	for _, p := range r.history {
+		if now.Sub(p.at) < dailyCapWindow {
+			recent = append(recent, p)
+			total += p.amount
+		}
+	}
+	r.history = recent
+	return total
+}
+
+func (r *Refiller) refill(w *Wallet, balance uint64) error {
+	amount := r.cfg.RefillAmount
+	now := time.Now()
+	if r.cfg.RefillDailyMax > 0 && r.refilledToday(now)+amount > r.cfg.RefillDailyMax {
+		if !r.limitAlerted {
+			r.limitAlerted = true
+			NotifyOperators(r.notifier, r.cfg, fmt.Sprintf("🚨 Hot wallet `%v` is low (%v) but the daily refill limit of %v is reached. Please refill it manually.",
+				w.Address.String(), balance, r.cfg.RefillDailyMax))
+		}
+		return fmt.Errorf("daily refill limit of %v reached", r.cfg.RefillDailyMax)
+	}
+	r.limitAlerted = false
+
+	reserve, err := r.backend.AccountState(r.reserve.Address)
+	if err != nil {
+		return fmt.Errorf("failed to read reserve wallet %v", err)
+	}
+	if reserve.StateProjected.GetBalance().GetValue() < amount+refillGas {
+		NotifyOperators(r.notifier, r.cfg, fmt.Sprintf("🚨 Reserve wallet `%v` can not refill hot wallet `%v`, insufficient funds.",
+			r.reserve.Address.String(), w.Address.String()))
+		return fmt.Errorf("insufficient funds in reserve wallet")
+	}
+
+	nonce := reserve.StateProjected.GetCounter()
+	entry := &AuditEntry{
+		Network:   r.cfg.Network,
+		Requester: "refill",
+		Address:   w.Address.String(),
+		Wallet:    r.reserve.Address.String(),
+		Amount:    amount,
+		Nonce:     nonce,
+		Decision:  AuditRefill,
+		Reason:    fmt.Sprintf("balance %v below %v", balance, r.cfg.RefillThreshold),
+	}
+	txState, err := r.backend.Transfer(w.Address, nonce, amount, refillGas, 100, r.reserve.Key)
+	if err == nil {
+		entry.TxID = "0x" + Bytes2Hex(txState.Id.Id)
+		entry.State = transactionStateDisStringsMap[int32(txState.State.Number())]
+		if txState.State <= apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING {
+			err = fmt.Errorf("tx rejected by node, %v", entry.State)
+		}
+	}
+	if err != nil {
+		entry.Decision = AuditRejected
+		entry.Reason = err.Error()
+	}
+	if auditErr := r.auditLog.Append(entry); auditErr != nil {
+		logger.Error("failed to write audit log", zap.Error(auditErr))
+	}
+	if err != nil {
+		NotifyOperators(r.notifier, r.cfg, fmt.Sprintf("🚫 Refill of hot wallet `%v` failed: %v", w.Address.String(), err))
+		return fmt.Errorf("refill transfer failed %v", err)
+	}
+
+	r.mu.Lock()
+	r.history = append(r.history, payout{at: now, amount: amount})
+	r.mu.Unlock()
+	logger.Info("refilled hot wallet", zap.String("wallet", w.Address.String()), zap.Uint64("amount", amount), zap.Uint64("balance", balance), zap.String("tx_id", entry.TxID))
+	msg := fmt.Sprintf("🔄 Refilled hot wallet `%v` with %v from the reserve (balance was %v)\ntxID: %v", w.Address.String(), amount, balance, entry.TxID)
+	NotifyOperators(r.notifier, r.cfg, msg)
+	return nil
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRefillDailyMaxSurvivesRestart(t *testing.T) {
+	cfg := testConfig()
+	cfg.RefillThreshold = 1000
+	cfg.RefillAmount = 500
+	cfg.RefillDailyMax = 1000
+	hot, reserve := testWallet(1), testWallet(2)
+	client := newFakeClient()
+	client.setAccount(hot.Address, 0, 0, 0, 0)
+	client.setAccount(reserve.Address, 100000, 0, 100000, 0)
+
+	r := NewRefiller(client, reserve, []*Wallet{hot}, nil, cfg)
+	for i := 0; i < 3; i++ {
+		r.check()
+	}
+	if len(client.transfers) != 2 {
+		t.Fatalf("%v refills, want 2 up to the daily max", len(client.transfers))
+	}
+
+	store, err := OpenStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetNetwork(cfg.Network, &NetworkState{Refills: r.Refills()})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenStateStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewRefiller(client, reserve, []*Wallet{hot}, nil, cfg)
+	restarted.Restore(reopened.Network(cfg.Network).Refills)
+	restarted.check()
+	if len(client.transfers) != 2 {
+		t.Errorf("refilled after a restart beyond the daily max, %v refills", len(client.transfers))
+	}
+
+	// without the saved refills the limit starts over
+	NewRefiller(client, reserve, []*Wallet{hot}, nil, cfg).check()
+	if len(client.transfers) != 3 {
+		t.Errorf("%v refills, want a refill with a fresh history", len(client.transfers))
+	}
+}
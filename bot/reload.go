@@ -0,0 +1,81 @@
+package bot
+
+import "reflect"
+
+// config returns the config the bot currently runs with.
+func (b *botBackend) config() BaseConfig {
+	b.cfgMu.RLock()
+	defer b.cfgMu.RUnlock()
+	return b.cfg
+}
+
+// SetConfig replaces the config of the bot. Requests in flight finish with the
+// config they started with.
+func (b *botBackend) SetConfig(cfg BaseConfig) {
+	b.cfgMu.Lock()
+	defer b.cfgMu.Unlock()
+	b.cfg = cfg
+}
+
+// ReloadConfig returns cur with the changes in next that can be applied to a
+// running bot: amounts, cooldowns, daily caps, fee, tiers, the channels of the
+// networks, the guild admin roles and language and the api keys. It also
+// returns the keys of the other changes, which need a restart and are left out.
+func ReloadConfig(cur, next BaseConfig) (BaseConfig, []string) {
+	applied := cur
+	applied.TransferAmount = next.TransferAmount
+	applied.RequestCoolDown = next.RequestCoolDown
+	applied.DailyCap = next.DailyCap
+	applied.Fee = next.Fee
+	applied.Tiers = next.Tiers
+	applied.AdminRoles = next.AdminRoles
+	applied.Language = next.Language
+	applied.APIKeys = next.APIKeys
+
+	var rejected []string
+	if sameNetworks(cur.Networks, next.Networks) {
+		applied.Networks = make([]NetworkConfig, len(cur.Networks))
+		for i, n := range cur.Networks {
+			n.Channels = next.Networks[i].Channels
+			n.TransferAmount = next.Networks[i].TransferAmount
+			n.RequestCoolDown = next.Networks[i].RequestCoolDown
+			applied.Networks[i] = n
+			rejected = append(rejected, changedKeys("networks."+n.Name+".", n, next.Networks[i])...)
+		}
+	} else {
+		rejected = append(rejected, "networks")
+	}
+	next.Networks = applied.Networks
+	rejected = append(rejected, changedKeys("", applied, next)...)
+	return applied, rejected
+}
+
+// sameNetworks returns true if a and b configure the same networks in the same order.
+func sameNetworks(a, b []NetworkConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// changedKeys returns the config keys, prefixed by prefix, of the fields
+// differing between the structs a and b.
+func changedKeys(prefix string, a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var keys []string
+	for i := 0; i < va.NumField(); i++ {
+		key := va.Type().Field(i).Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			keys = append(keys, prefix+key)
+		}
+	}
+	return keys
+}
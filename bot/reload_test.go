@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReloadConfig(t *testing.T) {
+	cur := validConfig()
+	cur.BotToken = "token"
+	cur.Networks = []NetworkConfig{{Name: "devnet", Channels: []string{"c1"}, TransferAmount: 5}}
+
+	next := cur
+	next.TransferAmount = 200
+	next.RequestCoolDown = 2 * time.Hour
+	next.Fee = 7
+	next.AdminRoles = []string{"admin"}
+	next.Language = "es"
+	next.APIKeys = []string{"key"}
+	next.Tiers = []TierConfig{{Name: "vip", Roles: []string{"r"}, TransferAmount: 1000, RequestCoolDown: time.Minute}}
+	next.BotToken = "other token"
+	next.Server = "other:9092"
+	next.Networks = []NetworkConfig{{Name: "devnet", Channels: []string{"c2"}, TransferAmount: 6, Mnemonic: testMnemonic}}
+
+	applied, rejected := ReloadConfig(cur, next)
+	if applied.TransferAmount != 200 || applied.RequestCoolDown != 2*time.Hour || applied.Fee != 7 || len(applied.Tiers) != 1 ||
+		len(applied.AdminRoles) != 1 || applied.Language != "es" || len(applied.APIKeys) != 1 {
+		t.Errorf("safe changes not applied: %+v", applied)
+	}
+	if applied.BotToken != "token" || applied.Server != "localhost:9092" {
+		t.Errorf("unsafe changes applied: %+v", applied)
+	}
+	n := applied.Networks[0]
+	if !reflect.DeepEqual(n.Channels, []string{"c2"}) || n.TransferAmount != 6 || n.Mnemonic != "" {
+		t.Errorf("network changes = %+v", n)
+	}
+	want := []string{"networks.devnet.mnemonic", "server", "token"}
+	if !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected = %v, want %v", rejected, want)
+	}
+	if cur.Networks[0].TransferAmount != 5 {
+		t.Error("reload changed the current config")
+	}
+
+	next.Networks = append(next.Networks, NetworkConfig{Name: "mainnet"})
+	applied, rejected = ReloadConfig(cur, next)
+	if len(applied.Networks) != 1 || rejected[0] != "networks" {
+		t.Errorf("added network: networks = %v, rejected = %v", applied.Networks, rejected)
+	}
+}
+
+func TestBotSetConfig(t *testing.T) {
+	b, _, clock := newTestBot(t, testConfig())
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.TransferAmount = 300
+	cfg.RequestCoolDown = time.Minute
+	b.SetConfig(cfg)
+
+	clock.advance(2 * time.Minute)
+	resp, err := b.Handle(transferRequest("bob", otherTestAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount := resp.Data.(*TransferResult).Amount; amount != 300 {
+		t.Errorf("amount = %v, want the reloaded 300", amount)
+	}
+}
+
+func TestRouterSetChannels(t *testing.T) {
+	r := NewNetworkRouter()
+	devnet, _, _ := newTestBot(t, testConfig())
+	testnet, _, _ := newTestBot(t, testConfig())
+	r.Add("devnet", devnet, nil)
+	r.Add("testnet", testnet, []string{"c1"})
+
+	r.SetChannels("testnet", []string{"c2"})
+	req := command(help)
+	for ch, want := range map[string]string{"c1": "devnet", "c2": "testnet"} {
+		req.ChannelID = ch
+		resp, err := r.Handle(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Network != want {
+			t.Errorf("channel %v routed to %v, want %v", ch, resp.Network, want)
+		}
+	}
+}
+
+func TestReloadGuildDefaults(t *testing.T) {
+	cfg := testConfig()
+	b, _, _ := newTestBot(t, cfg)
+	g := NewGuildHandler(b, cfg, nil)
+	if _, err := g.Handle(guildRequest([]string{"admin"}, guildCommand, "set", "language", "es")); err != errNotGuildAdmin {
+		t.Fatalf("error without admin roles = %v", err)
+	}
+
+	cfg.AdminRoles = []string{"admin"}
+	cfg.Language = "es"
+	g.SetConfig(cfg)
+	if guild := g.guild("g2"); guild.language() != "es" {
+		t.Errorf("guild language = %v, want the reloaded es", guild.language())
+	}
+	if _, err := g.Handle(guildRequest([]string{"admin"}, guildCommand, "set", "language", "en")); err != nil {
+		t.Errorf("reloaded admin role: %v", err)
+	}
+}
+
+func TestReloadAPIKeys(t *testing.T) {
+	cfg := testConfig()
+	cfg.APIKeys = []string{"old"}
+	f := NewHTTPFrontend(cfg)
+
+	cfg.APIKeys = []string{"new"}
+	f.SetConfig(cfg)
+	for key, want := range map[string]bool{"old": false, "new": true} {
+		r := httptest.NewRequest("POST", apiPrefix+"fund", nil)
+		r.Header.Set("X-API-Key", key)
+		if _, ok := f.requesterID(r); ok != want {
+			t.Errorf("key %v accepted = %v, want %v", key, ok, want)
+		}
+	}
+}
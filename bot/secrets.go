@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	envSecretPrefix  = "env:"
+	fileSecretPrefix = "file:"
+	redactedValue    = "[REDACTED]"
+)
+
+// resolveSecret returns the value referenced by a secret config entry.
+// "env:VAR_NAME" reads the environment variable VAR_NAME, "file:/path" reads the
+// file content (trailing whitespace trimmed), anything else is returned as is.
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, envSecretPrefix):
+		name := strings.TrimPrefix(ref, envSecretPrefix)
+		if name == "" {
+			return "", fmt.Errorf("empty environment variable name")
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %v is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(ref, fileSecretPrefix):
+		path := strings.TrimPrefix(ref, fileSecretPrefix)
+		if path == "" {
+			return "", fmt.Errorf("empty secret file path")
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %v", err)
+		}
+		return strings.TrimRight(string(data), " \t\r\n"), nil
+	}
+	return ref, nil
+}
+
+// resolveSecrets replaces secret references in the config with their values.
+func (c *BaseConfig) resolveSecrets() error {
+	secrets := map[string]*string{
+		"token":            &c.BotToken,
+		"telegram-token":   &c.TelegramToken,
+		"priv-key":         &c.PrivateKey,
+		"mnemonic":         &c.Mnemonic,
+		"reserve-priv-key": &c.ReservePrivateKey,
+		"reserve-mnemonic": &c.ReserveMnemonic,
+	}
+	for i := range c.Networks {
+		n := &c.Networks[i]
+		secrets[n.Name+".priv-key"] = &n.PrivateKey
+		secrets[n.Name+".mnemonic"] = &n.Mnemonic
+		secrets[n.Name+".reserve-priv-key"] = &n.ReservePrivateKey
+		secrets[n.Name+".reserve-mnemonic"] = &n.ReserveMnemonic
+	}
+	c.APIKeys = append([]string(nil), c.APIKeys...)
+	for i := range c.APIKeys {
+		secrets[fmt.Sprintf("api-keys[%v]", i)] = &c.APIKeys[i]
+	}
+	for name, field := range secrets {
+		val, err := resolveSecret(*field)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %v: %v", name, err)
+		}
+		*field = val
+	}
+	return nil
+}
+
+// redact hides a secret value while still showing whether it was set.
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// Redacted returns a copy of the config that is safe to print or log.
+func (c BaseConfig) Redacted() BaseConfig {
+	c.BotToken = redact(c.BotToken)
+	c.TelegramToken = redact(c.TelegramToken)
+	c.PrivateKey = redact(c.PrivateKey)
+	c.Mnemonic = redact(c.Mnemonic)
+	c.ReservePrivateKey = redact(c.ReservePrivateKey)
+	c.ReserveMnemonic = redact(c.ReserveMnemonic)
+	if c.APIKeys != nil {
+		keys := make([]string, len(c.APIKeys))
+		for i, key := range c.APIKeys {
+			keys[i] = redact(key)
+		}
+		c.APIKeys = keys
+	}
+	c.Networks = append([]NetworkConfig(nil), c.Networks...)
+	for i := range c.Networks {
+		c.Networks[i].PrivateKey = redact(c.Networks[i].PrivateKey)
+		c.Networks[i].Mnemonic = redact(c.Networks[i].Mnemonic)
+		c.Networks[i].ReservePrivateKey = redact(c.Networks[i].ReservePrivateKey)
+		c.Networks[i].ReserveMnemonic = redact(c.Networks[i].ReserveMnemonic)
+	}
+	return c
+}
+
+// WithoutReserve returns a copy of the config without the reserve wallet keys,
+// which are only handed to the refiller.
+func (c BaseConfig) WithoutReserve() BaseConfig {
+	c.ReservePrivateKey, c.ReserveMnemonic = "", ""
+	c.Networks = append([]NetworkConfig(nil), c.Networks...)
+	for i := range c.Networks {
+		c.Networks[i].ReservePrivateKey, c.Networks[i].ReserveMnemonic = "", ""
+	}
+	return c
+}
+
+// String implements fmt.Stringer so printing a config never leaks secrets.
+func (c BaseConfig) String() string {
+	type plain BaseConfig
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+// GoString implements fmt.GoStringer for %#v.
+func (c BaseConfig) GoString() string {
+	return c.String()
+}
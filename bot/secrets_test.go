@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithoutReserve(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReserveMnemonic = testMnemonic
+	cfg.ReservePrivateKey = "reserve-key"
+	cfg.Networks = []NetworkConfig{{Name: "devnet", ReserveMnemonic: testMnemonic, ReservePrivateKey: "reserve-key"}}
+
+	stripped := cfg.WithoutReserve()
+	if stripped.ReserveMnemonic != "" || stripped.ReservePrivateKey != "" {
+		t.Error("reserve keys kept")
+	}
+	if n := stripped.Networks[0]; n.ReserveMnemonic != "" || n.ReservePrivateKey != "" {
+		t.Error("network reserve keys kept")
+	}
+	if cfg.ReserveMnemonic == "" || cfg.Networks[0].ReservePrivateKey == "" {
+		t.Error("reserve keys cleared in the original config")
+	}
+}
+
+func TestAPIKeySecrets(t *testing.T) {
+	setEnv(t, "TAPBOT_TEST_API_KEY", "from-env")
+	cfg := testConfig()
+	cfg.APIKeys = []string{"plain", "env:TAPBOT_TEST_API_KEY"}
+	if err := cfg.resolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIKeys[0] != "plain" || cfg.APIKeys[1] != "from-env" {
+		t.Errorf("api keys = %v, want the env reference resolved", cfg.APIKeys)
+	}
+
+	s := cfg.String()
+	if strings.Contains(s, "plain") || strings.Contains(s, "from-env") {
+		t.Errorf("api keys printed: %v", s)
+	}
+	if cfg.APIKeys[0] != "plain" {
+		t.Error("api keys redacted in the original config")
+	}
+
+	cfg.APIKeys = []string{"env:TAPBOT_TEST_UNSET_API_KEY"}
+	if err := cfg.resolveSecrets(); err == nil {
+		t.Error("unset api key variable accepted")
+	}
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is returned for requests received while the bot shuts down.
+var ErrShuttingDown = errors.New("the faucet is going offline, please try again later")
+
+// Gate is a Handler letting requests through to h until it is closed. Wait
+// returns once the requests let through before are done, so the bot can shut
+// down without abandoning transfers in flight.
+type Gate struct {
+	h      Handler
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewGate(h Handler) *Gate {
+	return &Gate{h: h}
+}
+
+func (g *Gate) Handle(req *Request) (*Response, error) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	g.wg.Add(1)
+	g.mu.Unlock()
+	defer g.wg.Done()
+	return g.h.Handle(req)
+}
+
+// Close rejects all further requests.
+func (g *Gate) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// Wait waits for the requests in flight until ctx is done.
+func (g *Gate) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
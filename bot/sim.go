@@ -0,0 +1,386 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultSimLayerDuration = 10 * time.Second
+
+// Client method names used to inject failures into a SimLedger.
+const (
+	SimNodeStatus          = "NodeStatus"
+	SimAccountState        = "AccountState"
+	SimTransfer            = "Transfer"
+	SimTransactionState    = "TransactionState"
+	SimGetMeshTransactions = "GetMeshTransactions"
+	SimSubscribe           = "SubscribeTransactionState"
+)
+
+type simAccountState struct {
+	balance uint64
+	counter uint64
+}
+
+type simAccount struct {
+	current   simAccountState
+	projected simAccountState
+}
+
+type simTx struct {
+	tx    *apitypes.Transaction
+	state apitypes.TransactionState_TransactionState
+	// layer is the layer the tx was submitted in until it is on the mesh,
+	// then the layer it was included in
+	layer uint32
+}
+
+type simFailure struct {
+	count int
+	err   error
+}
+
+// SimLedger is a Client backed by an in-memory ledger instead of a node, for
+// demos and local development. Layers advance with time. A transaction is in
+// the mempool in the layer it is submitted in, on the mesh in the next layer
+// and processed in the layer after. Failures can be injected per method.
+type SimLedger struct {
+	mu            sync.Mutex
+	now           func() time.Time
+	genesis       time.Time
+	layerDuration time.Duration
+	layer         uint32
+
+	accounts map[gosmtypes.Address]*simAccount
+	txs      map[string]*simTx
+	// pending are the ids of txs not processed yet, in submit order
+	pending []string
+
+	synced   bool
+	peers    uint64
+	failRate float64
+	failures map[string]*simFailure
+	rejects  int
+}
+
+// NewSimLedger returns a synced ledger at layer 0 with layers of layerDuration.
+func NewSimLedger(layerDuration time.Duration) *SimLedger {
+	if layerDuration <= 0 {
+		layerDuration = defaultSimLayerDuration
+	}
+	s := &SimLedger{
+		now:           time.Now,
+		layerDuration: layerDuration,
+		accounts:      make(map[gosmtypes.Address]*simAccount),
+		txs:           make(map[string]*simTx),
+		synced:        true,
+		peers:         8,
+		failures:      make(map[string]*simFailure),
+	}
+	s.genesis = s.now()
+	return s
+}
+
+// Fund adds amount to the balance of address.
+func (s *SimLedger) Fund(address gosmtypes.Address, amount uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc := s.account(address)
+	acc.current.balance += amount
+	acc.projected.balance += amount
+}
+
+// SetSynced sets the sync state reported by NodeStatus.
+func (s *SimLedger) SetSynced(synced bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = synced
+}
+
+// SetFailRate makes every call fail as unreachable with probability rate.
+func (s *SimLedger) SetFailRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failRate = rate
+}
+
+// FailNext makes the next count calls of method return err. A nil err
+// fails as an unreachable node.
+func (s *SimLedger) FailNext(method string, count int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		err = status.Error(codes.Unavailable, "simulated node unavailable")
+	}
+	s.failures[method] = &simFailure{count: count, err: err}
+}
+
+// RejectNext makes the mempool reject the next count transfers.
+func (s *SimLedger) RejectNext(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects = count
+}
+
+func (s *SimLedger) account(address gosmtypes.Address) *simAccount {
+	acc, ok := s.accounts[address]
+	if !ok {
+		acc = &simAccount{}
+		s.accounts[address] = acc
+	}
+	return acc
+}
+
+// fail returns the injected failure of method, if any. Must be called with s.mu held.
+func (s *SimLedger) fail(method string) error {
+	if f, ok := s.failures[method]; ok && f.count > 0 {
+		f.count--
+		return f.err
+	}
+	if s.failRate > 0 && rand.Float64() < s.failRate {
+		return status.Error(codes.Unavailable, "simulated node unavailable")
+	}
+	return nil
+}
+
+// advance moves the ledger to the current layer and the pending txs along
+// their states. Must be called with s.mu held.
+func (s *SimLedger) advance() {
+	s.layer = uint32(s.now().Sub(s.genesis) / s.layerDuration)
+	pending := s.pending[:0]
+	for _, id := range s.pending {
+		t := s.txs[id]
+		if t.state == apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL && s.layer > t.layer {
+			t.state = apitypes.TransactionState_TRANSACTION_STATE_MESH
+			t.layer++
+		}
+		if t.state == apitypes.TransactionState_TRANSACTION_STATE_MESH && s.layer > t.layer {
+			t.state = apitypes.TransactionState_TRANSACTION_STATE_PROCESSED
+			s.apply(t.tx)
+			continue
+		}
+		pending = append(pending, id)
+	}
+	s.pending = pending
+}
+
+// apply applies a processed tx to the current state.
+func (s *SimLedger) apply(tx *apitypes.Transaction) {
+	sender := s.account(gosmtypes.BytesToAddress(tx.Sender.Address))
+	sender.current.balance -= tx.Amount.Value + tx.GasOffered.GasPrice
+	sender.current.counter++
+	receiver := s.account(gosmtypes.BytesToAddress(tx.GetCoinTransfer().Receiver.Address))
+	receiver.current.balance += tx.Amount.Value
+}
+
+func (s *SimLedger) NodeStatus() (*apitypes.NodeStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimNodeStatus); err != nil {
+		return nil, err
+	}
+	s.advance()
+	verified := s.layer
+	if verified > 0 {
+		verified--
+	}
+	return &apitypes.NodeStatus{
+		ConnectedPeers: s.peers,
+		IsSynced:       s.synced,
+		SyncedLayer:    &apitypes.LayerNumber{Number: s.layer},
+		TopLayer:       &apitypes.LayerNumber{Number: s.layer},
+		VerifiedLayer:  &apitypes.LayerNumber{Number: verified},
+	}, nil
+}
+
+func (s *SimLedger) AccountState(address gosmtypes.Address) (*apitypes.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimAccountState); err != nil {
+		return nil, err
+	}
+	s.advance()
+	acc := s.account(address)
+	return &apitypes.Account{
+		AccountId: &apitypes.AccountId{Address: address.Bytes()},
+		StateCurrent: &apitypes.AccountState{
+			Counter: acc.current.counter,
+			Balance: &apitypes.Amount{Value: acc.current.balance},
+		},
+		StateProjected: &apitypes.AccountState{
+			Counter: acc.projected.counter,
+			Balance: &apitypes.Amount{Value: acc.projected.balance},
+		},
+	}, nil
+}
+
+// Transfer validates the tx against the projected state of the sender like the
+// mempool of a node does, and returns the rejection as the tx state.
+func (s *SimLedger) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimTransfer); err != nil {
+		return nil, err
+	}
+	s.advance()
+
+	senderAddress := gosmtypes.BytesToAddress(key.Public().(ed25519.PublicKey))
+	tx := &apitypes.Transaction{
+		Datum: &apitypes.Transaction_CoinTransfer{CoinTransfer: &apitypes.CoinTransferTransaction{
+			Receiver: &apitypes.AccountId{Address: recipient.Bytes()},
+		}},
+		Sender:     &apitypes.AccountId{Address: senderAddress.Bytes()},
+		GasOffered: &apitypes.GasOffered{GasProvided: gasLimit, GasPrice: gasPrice},
+		Amount:     &apitypes.Amount{Value: amount},
+		Counter:    nonce,
+	}
+	tx.Id = &apitypes.TransactionId{Id: simTxID(senderAddress, recipient, nonce, amount)}
+
+	sender := s.account(senderAddress)
+	state := apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL
+	switch {
+	case s.rejects > 0:
+		s.rejects--
+		state = apitypes.TransactionState_TRANSACTION_STATE_REJECTED
+	case nonce != sender.projected.counter:
+		state = apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING
+	case sender.projected.balance < amount+gasPrice:
+		state = apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS
+	}
+	res := &apitypes.TransactionState{Id: tx.Id, State: state}
+	if state != apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL {
+		return res, nil
+	}
+
+	sender.projected.balance -= amount + gasPrice
+	sender.projected.counter++
+	s.account(recipient).projected.balance += amount
+	id := string(tx.Id.Id)
+	s.txs[id] = &simTx{tx: tx, state: state, layer: s.layer}
+	s.pending = append(s.pending, id)
+	return res, nil
+}
+
+func (s *SimLedger) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimTransactionState); err != nil {
+		return nil, nil, err
+	}
+	s.advance()
+	t, ok := s.txs[string(txId)]
+	if !ok {
+		return nil, nil, status.Error(codes.NotFound, "transaction not found")
+	}
+	state := &apitypes.TransactionState{Id: t.tx.Id, State: t.state}
+	if !includeTx {
+		return state, nil, nil
+	}
+	return state, t.tx, nil
+}
+
+// GetMeshTransactions returns the txs on the mesh sent from or to address, oldest first.
+func (s *SimLedger) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimGetMeshTransactions); err != nil {
+		return nil, 0, err
+	}
+	s.advance()
+
+	var txs []*apitypes.MeshTransaction
+	for _, t := range s.txs {
+		if t.state < apitypes.TransactionState_TRANSACTION_STATE_MESH {
+			continue
+		}
+		sender := gosmtypes.BytesToAddress(t.tx.Sender.Address)
+		receiver := gosmtypes.BytesToAddress(t.tx.GetCoinTransfer().Receiver.Address)
+		if sender != address && receiver != address {
+			continue
+		}
+		txs = append(txs, &apitypes.MeshTransaction{Transaction: t.tx, LayerId: &apitypes.LayerNumber{Number: t.layer}})
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].LayerId.Number != txs[j].LayerId.Number {
+			return txs[i].LayerId.Number < txs[j].LayerId.Number
+		}
+		return txs[i].Transaction.Counter < txs[j].Transaction.Counter
+	})
+
+	total := uint32(len(txs))
+	if offset >= total {
+		return nil, total, nil
+	}
+	txs = txs[offset:]
+	if maxResults > 0 && uint32(len(txs)) > maxResults {
+		txs = txs[:maxResults]
+	}
+	return txs, total, nil
+}
+
+// SubscribeTransactionState streams the state changes of a tx, checking the
+// ledger several times per layer.
+func (s *SimLedger) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail(SimSubscribe); err != nil {
+		return nil, err
+	}
+	t, ok := s.txs[string(txId)]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+
+	states := make(chan *apitypes.TransactionState)
+	last := t.state
+	go func() {
+		defer close(states)
+		ticker := time.NewTicker(s.layerDuration / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			s.mu.Lock()
+			s.advance()
+			state := t.state
+			s.mu.Unlock()
+			if state == last {
+				continue
+			}
+			last = state
+			select {
+			case states <- &apitypes.TransactionState{Id: t.tx.Id, State: state}:
+			case <-ctx.Done():
+				return
+			}
+			if state == apitypes.TransactionState_TRANSACTION_STATE_PROCESSED {
+				return
+			}
+		}
+	}()
+	return states, nil
+}
+
+func simTxID(sender, recipient gosmtypes.Address, nonce, amount uint64) []byte {
+	h := sha256.New()
+	h.Write(sender.Bytes())
+	h.Write(recipient.Bytes())
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], nonce)
+	binary.BigEndian.PutUint64(buf[8:], amount)
+	h.Write(buf[:])
+	return h.Sum(nil)
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+// newTestSim returns a ledger with 1s layers driven by a fake clock, with the
+// faucet wallet funded with 10000.
+func newTestSim(t *testing.T) (*SimLedger, *fakeClock) {
+	t.Helper()
+	clock := newFakeClock()
+	s := NewSimLedger(time.Second)
+	s.now = clock.now
+	s.genesis = clock.now()
+	s.Fund(testWallet(1).Address, 10000)
+	return s, clock
+}
+
+func simState(t *testing.T, s *SimLedger, id []byte) apitypes.TransactionState_TransactionState {
+	t.Helper()
+	state, _, err := s.TransactionState(id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state.State
+}
+
+func TestSimLedgerLayers(t *testing.T) {
+	s, clock := newTestSim(t)
+	for _, step := range []struct {
+		advance time.Duration
+		layer   uint32
+	}{
+		{0, 0},
+		{500 * time.Millisecond, 0},
+		{500 * time.Millisecond, 1},
+		{2 * time.Second, 3},
+	} {
+		clock.advance(step.advance)
+		st, err := s.NodeStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.TopLayer.Number != step.layer || !st.IsSynced {
+			t.Errorf("status = %+v, want synced at layer %v", st, step.layer)
+		}
+		if step.layer > 0 && st.VerifiedLayer.Number != step.layer-1 {
+			t.Errorf("verified layer = %v, want %v", st.VerifiedLayer.Number, step.layer-1)
+		}
+	}
+	s.SetSynced(false)
+	if st, _ := s.NodeStatus(); st.IsSynced {
+		t.Error("ledger synced after SetSynced(false)")
+	}
+}
+
+func TestSimLedgerTransfer(t *testing.T) {
+	s, clock := newTestSim(t)
+	faucet, to := testWallet(1), testWallet(2).Address
+
+	res, err := s.Transfer(to, 0, 100, 1, 100, faucet.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL {
+		t.Fatalf("state = %v, want mempool", res.State)
+	}
+	acc, _ := s.AccountState(faucet.Address)
+	if acc.StateProjected.Counter != 1 || acc.StateProjected.Balance.Value != 9899 || acc.StateCurrent.Balance.Value != 10000 {
+		t.Errorf("account after submit = %+v", acc)
+	}
+
+	// mempool in the submit layer, mesh in the next and processed in the one after
+	clock.advance(time.Second)
+	if state := simState(t, s, res.Id.Id); state != apitypes.TransactionState_TRANSACTION_STATE_MESH {
+		t.Errorf("state in the next layer = %v, want mesh", state)
+	}
+	if txs, total, _ := s.GetMeshTransactions(to, 0, 10); total != 1 || txs[0].LayerId.Number != 1 {
+		t.Errorf("mesh txs = %v (%v), want the tx in layer 1", txs, total)
+	}
+	clock.advance(time.Second)
+	if state := simState(t, s, res.Id.Id); state != apitypes.TransactionState_TRANSACTION_STATE_PROCESSED {
+		t.Errorf("state two layers later = %v, want processed", state)
+	}
+	acc, _ = s.AccountState(faucet.Address)
+	if acc.StateCurrent.Counter != 1 || acc.StateCurrent.Balance.Value != 9899 {
+		t.Errorf("account after processing = %+v", acc)
+	}
+	if acc, _ := s.AccountState(to); acc.StateCurrent.Balance.Value != 100 {
+		t.Errorf("receiver balance = %v, want 100", acc.StateCurrent.Balance.Value)
+	}
+
+	state, tx, err := s.TransactionState(res.Id.Id, true)
+	if err != nil || tx.GetCoinTransfer() == nil || state.Id == nil {
+		t.Errorf("tx = %v, %v, %v", state, tx, err)
+	}
+	if _, _, err := s.TransactionState([]byte{0xde, 0xad}, true); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown tx error = %v, want not found", err)
+	}
+}
+
+func TestSimLedgerRejections(t *testing.T) {
+	s, _ := newTestSim(t)
+	faucet, to := testWallet(1), testWallet(2).Address
+
+	tests := []struct {
+		name   string
+		nonce  uint64
+		amount uint64
+		want   apitypes.TransactionState_TransactionState
+	}{
+		{"nonce ahead", 1, 100, apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING},
+		{"insufficient funds", 0, 10000, apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS},
+		{"valid", 0, 100, apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL},
+		{"nonce reused", 0, 100, apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING},
+	}
+	for _, tc := range tests {
+		res, err := s.Transfer(to, tc.nonce, tc.amount, 1, 100, faucet.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.State != tc.want {
+			t.Errorf("%v: state = %v, want %v", tc.name, res.State, tc.want)
+		}
+	}
+
+	s.RejectNext(1)
+	if res, _ := s.Transfer(to, 1, 100, 1, 100, faucet.Key); res.State != apitypes.TransactionState_TRANSACTION_STATE_REJECTED {
+		t.Errorf("state = %v, want rejected", res.State)
+	}
+	// rejected txs leave the projected state alone
+	if res, _ := s.Transfer(to, 1, 100, 1, 100, faucet.Key); res.State != apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL {
+		t.Errorf("state after the rejection = %v, want mempool", res.State)
+	}
+}
+
+func TestSimLedgerFailures(t *testing.T) {
+	s, _ := newTestSim(t)
+	s.FailNext(SimAccountState, 2, nil)
+	for i := 0; i < 2; i++ {
+		if _, err := s.AccountState(testWallet(1).Address); !isConnectionError(err) {
+			t.Errorf("call %v error = %v, want unavailable", i, err)
+		}
+	}
+	if _, err := s.AccountState(testWallet(1).Address); err != nil {
+		t.Errorf("error after the injected failures = %v", err)
+	}
+	// other methods are not affected
+	if _, err := s.NodeStatus(); err != nil {
+		t.Error(err)
+	}
+
+	s.FailNext(SimTransfer, 1, status.Error(codes.InvalidArgument, "bad tx"))
+	if _, err := s.Transfer(testWallet(2).Address, 0, 100, 1, 100, testWallet(1).Key); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("error = %v, want the injected error", err)
+	}
+
+	s.SetFailRate(1)
+	if _, err := s.NodeStatus(); !isConnectionError(err) {
+		t.Errorf("error with fail rate 1 = %v, want unavailable", err)
+	}
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is the bot state kept across restarts.
+type State struct {
+	Networks map[string]*NetworkState `json:"networks"`
+	// Guilds are the configs set by the admins of discord guilds
+	Guilds map[string]*GuildConfig `json:"guilds,omitempty"`
+}
+
+// NetworkState is the state of the bot of a network.
+type NetworkState struct {
+	Backoff   map[string]time.Time      `json:"backoff"`
+	Payouts   map[string][]PayoutRecord `json:"payouts"`
+	Recent    []TransferResult          `json:"recent"`
+	Confirmed uint64                    `json:"confirmed"`
+	Failed    uint64                    `json:"failed"`
+	// Tracked are the payouts that were not final yet
+	Tracked []TrackedTx `json:"tracked"`
+	// Refills are the reserve refills counted against refill-daily-max
+	Refills []PayoutRecord `json:"refills,omitempty"`
+}
+
+// PayoutRecord is a payout counted against a daily cap.
+type PayoutRecord struct {
+	Time   time.Time `json:"time"`
+	Amount uint64    `json:"amount"`
+}
+
+// TrackedTx is a payout tx that was tracked when the bot stopped.
+type TrackedTx struct {
+	TxID    string         `json:"tx_id"`
+	State   int32          `json:"state"`
+	Request *Request       `json:"request"`
+	Payout  TransferResult `json:"payout"`
+}
+
+// StateStore keeps the bot state in a JSON file.
+type StateStore struct {
+	path  string
+	mu    sync.Mutex
+	state State
+}
+
+// OpenStateStore loads the state saved at path, a missing file is an empty state.
+func OpenStateStore(path string) (*StateStore, error) {
+	s := &StateStore{path: path, state: State{Networks: make(map[string]*NetworkState)}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %v: %v", path, err)
+	}
+	if s.state.Networks == nil {
+		s.state.Networks = make(map[string]*NetworkState)
+	}
+	return s, nil
+}
+
+// Network returns the saved state of network name, nil if there is none or s
+// is nil.
+func (s *StateStore) Network(name string) *NetworkState {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Networks[name]
+}
+
+// SetNetwork sets the state of network name, it is written by the next Save.
+func (s *StateStore) SetNetwork(name string, st *NetworkState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Networks[name] = st
+}
+
+// Guilds returns a copy of the saved guild configs, empty if s is nil.
+func (s *StateStore) Guilds() map[string]*GuildConfig {
+	guilds := make(map[string]*GuildConfig)
+	if s == nil {
+		return guilds
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, g := range s.state.Guilds {
+		c := *g
+		guilds[id] = &c
+	}
+	return guilds
+}
+
+// SetGuild sets the config of guild id, it is written by the next Save.
+func (s *StateStore) SetGuild(id string, g *GuildConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Guilds == nil {
+		s.state.Guilds = make(map[string]*GuildConfig)
+	}
+	c := *g
+	s.state.Guilds[id] = &c
+}
+
+// Save writes the state to a temporary file and renames it over the state
+// file, so a crash while saving leaves the previous state intact.
+func (s *StateStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(&s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// State returns the cooldowns, daily cap payouts, recent payouts and payout
+// counters of the bot. Expired cooldowns and payouts are left out.
+func (b *botBackend) State() *NetworkState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	st := &NetworkState{
+		Backoff:   make(map[string]time.Time),
+		Payouts:   make(map[string][]PayoutRecord),
+		Recent:    append([]TransferResult{}, b.recent...),
+		Confirmed: b.confirmed,
+		Failed:    b.failed,
+	}
+	for key, ts := range b.backoff {
+		if now.Before(ts) {
+			st.Backoff[key] = ts
+		}
+	}
+	for requester := range b.payouts {
+		for _, p := range b.payouts[requester] {
+			if now.Sub(p.at) < dailyCapWindow {
+				st.Payouts[requester] = append(st.Payouts[requester], PayoutRecord{Time: p.at, Amount: p.amount})
+			}
+		}
+	}
+	return st
+}
+
+// Restore restores the state returned by State, except the tracked txs.
+func (b *botBackend) Restore(st *NetworkState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, ts := range st.Backoff {
+		b.backoff[key] = ts
+	}
+	for requester, records := range st.Payouts {
+		for _, r := range records {
+			b.payouts[requester] = append(b.payouts[requester], payout{at: r.Time, amount: r.Amount})
+		}
+	}
+	b.recent = append(b.recent, st.Recent...)
+	b.confirmed += st.Confirmed
+	b.failed += st.Failed
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := store.Network("testnet"); st != nil {
+		t.Fatalf("state %+v in a missing file", st)
+	}
+
+	b, _, _ := newTestBot(t, testConfig())
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	st := b.State()
+	st.Tracked = []TrackedTx{{TxID: "0x01", State: int32(txMempool), Request: transferRequest("alice", testAddress)}}
+	store.SetNetwork("testnet", st)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = store.Network("testnet")
+	if st == nil {
+		t.Fatal("no saved state")
+	}
+	if len(st.Backoff) != 2 || len(st.Payouts["alice"]) != 1 || len(st.Recent) != 1 {
+		t.Errorf("unexpected saved state %+v", st)
+	}
+	if len(st.Tracked) != 1 || st.Tracked[0].TxID != "0x01" || st.Tracked[0].Request.RequesterID != "alice" {
+		t.Errorf("unexpected tracked txs %+v", st.Tracked)
+	}
+}
+
+func TestRestoreKeepsCoolDown(t *testing.T) {
+	b, _, clock := newTestBot(t, testConfig())
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(30 * time.Minute)
+	st := b.State()
+
+	restarted, client, restartedClock := newTestBot(t, testConfig())
+	restartedClock.advance(30 * time.Minute)
+	restarted.Restore(st)
+
+	var cdErr *CoolDownError
+	_, err := restarted.Handle(transferRequest("alice", otherTestAddress))
+	if !errors.As(err, &cdErr) || cdErr.Retry != 30*time.Minute {
+		t.Errorf("error = %v, want a cooldown of 30m", err)
+	}
+	if n := len(client.sent()); n != 0 {
+		t.Errorf("sent %v transfers during the restored cooldown", n)
+	}
+	resp, err := restarted.Handle(command(recentPays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(resp.Data.(*RecentPayoutsResult).Payouts); n != 1 {
+		t.Errorf("%v recent payouts after restore, want 1", n)
+	}
+
+	// expired cooldowns are not saved
+	clock.advance(time.Hour)
+	if st := b.State(); len(st.Backoff) != 0 {
+		t.Errorf("expired cooldowns saved %v", st.Backoff)
+	}
+}
+
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) Handle(req *Request) (*Response, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return &Response{}, nil
+}
+
+func TestGate(t *testing.T) {
+	h := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	gate := NewGate(h)
+
+	done := make(chan error)
+	go func() {
+		_, err := gate.Handle(command(help))
+		done <- err
+	}()
+	<-h.started
+	gate.Close()
+	if _, err := gate.Handle(command(help)); err != ErrShuttingDown {
+		t.Errorf("error = %v, want %v", err, ErrShuttingDown)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := gate.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait with a request in flight = %v", err)
+	}
+
+	close(h.release)
+	if err := <-done; err != nil {
+		t.Errorf("request in flight failed: %v", err)
+	}
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Errorf("wait = %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync/atomic"
+)
+
+// ErrStreamUnsupported is returned by SubscribeTransactionState when the node
+// does not serve the transaction state stream.
+var ErrStreamUnsupported = errors.New("transaction state stream not supported")
+
+// StreamClient adds the transaction state stream of the node API to a
+// NodeClient, such as the smrepl wallet backend whose connection is private.
+type StreamClient struct {
+	NodeClient
+	conn *grpc.ClientConn
+	txs  apitypes.TransactionServiceClient
+	// unsupported is set once the node answered the stream as unimplemented
+	unsupported int32
+}
+
+// NewStreamClient opens a connection to server for the streams of c.
+func NewStreamClient(c NodeClient, server string, secure bool) (*StreamClient, error) {
+	creds := grpc.WithInsecure()
+	if secure {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	conn, err := grpc.Dial(server, creds)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamClient{NodeClient: c, conn: conn, txs: apitypes.NewTransactionServiceClient(conn)}, nil
+}
+
+// SubscribeTransactionState streams the state changes of a transaction. The
+// channel is closed when ctx is done or the stream fails.
+func (c *StreamClient) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	if atomic.LoadInt32(&c.unsupported) == 1 {
+		return nil, ErrStreamUnsupported
+	}
+	stream, err := c.txs.TransactionsStateStream(ctx, &apitypes.TransactionsStateStreamRequest{
+		TransactionId: []*apitypes.TransactionId{{Id: txId}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(chan *apitypes.TransactionState)
+	go func() {
+		defer close(states)
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				if status.Code(err) == codes.Unimplemented {
+					atomic.StoreInt32(&c.unsupported, 1)
+				}
+				return
+			}
+			select {
+			case states <- res.TransactionState:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return states, nil
+}
+
+// TransactionState always asks the node for the tx. The wallet backend indexes
+// the returned txs unchecked and the node only returns them if asked to.
+func (c *StreamClient) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	state, tx, err := c.NodeClient.TransactionState(txId, true)
+	if !includeTx {
+		tx = nil
+	}
+	return state, tx, err
+}
+
+// Close closes the stream connection and the connection of the NodeClient.
+func (c *StreamClient) Close() error {
+	if closer, ok := c.NodeClient.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	return c.conn.Close()
+}
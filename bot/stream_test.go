@@ -0,0 +1,24 @@
+package bot
+
+import (
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"testing"
+)
+
+func TestStreamClientTransactionState(t *testing.T) {
+	node := newFakeClient()
+	node.setState([]byte{0x01}, apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL)
+	c := &StreamClient{NodeClient: node}
+
+	// the fake panics like the wallet backend if the tx is not included
+	state, tx, err := c.TransactionState([]byte{0x01}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GetState() != apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL || tx != nil {
+		t.Errorf("state = %v, tx = %v, want mempool and no tx", state.GetState(), tx)
+	}
+	if _, tx, _ := c.TransactionState([]byte{0x01}, true); tx == nil {
+		t.Error("no tx with includeTx")
+	}
+}
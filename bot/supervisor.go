@@ -0,0 +1,310 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// ErrDegraded is returned for node calls while the connection to the node is
+// down and the bot reconnects.
+var ErrDegraded = errors.New("the faucet lost its connection to the node and is reconnecting, please try again later")
+
+// ConnState is the state of the connection to the node of a network.
+type ConnState int
+
+const (
+	ConnConnected ConnState = iota
+	// ConnDegraded is the state while the node can not be reached
+	ConnDegraded
+)
+
+func (s ConnState) String() string {
+	if s == ConnDegraded {
+		return "degraded"
+	}
+	return "connected"
+}
+
+// ConnListener is told when the connection to the node of a network breaks or
+// is restored.
+type ConnListener interface {
+	ConnStateChanged(network string, state ConnState)
+}
+
+// runner is a client with background work, such as the health checks of a NodePool.
+type runner interface {
+	Run(stop <-chan struct{})
+}
+
+// Supervisor is a Client keeping a connection to a node. It checks the node
+// every interval and after calls failing with connection errors. Once the node
+// can not be reached the connection is closed and dialed again with exponential
+// backoff, calls fail with ErrDegraded in the meantime.
+type Supervisor struct {
+	network  string
+	dial     func() (Client, error)
+	interval time.Duration
+	minDelay time.Duration
+	maxDelay time.Duration
+	// check asks Run for a health check
+	check chan struct{}
+
+	mu        sync.RWMutex
+	client    Client
+	state     ConnState
+	listeners []ConnListener
+}
+
+// NewSupervisor connects to the node of network with dial. The supervisor starts
+// degraded if the node can not be reached, Run keeps reconnecting.
+func NewSupervisor(network string, dial func() (Client, error), interval time.Duration) *Supervisor {
+	if interval <= 0 {
+		interval = defaultNodeCheckInterval
+	}
+	s := &Supervisor{
+		network:  network,
+		dial:     dial,
+		interval: interval,
+		minDelay: minReconnectDelay,
+		maxDelay: maxReconnectDelay,
+		check:    make(chan struct{}, 1),
+	}
+	c, err := s.connect()
+	if err != nil {
+		logger.Warn("node connection degraded", zap.String("network", network), zap.Error(err))
+		s.state = ConnDegraded
+		return s
+	}
+	s.client = c
+	return s
+}
+
+// AddListener adds l to the listeners told about state changes. l is told
+// right away if the connection is degraded.
+func (s *Supervisor) AddListener(l ConnListener) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, l)
+	state := s.state
+	s.mu.Unlock()
+	if state == ConnDegraded {
+		l.ConnStateChanged(s.network, state)
+	}
+}
+
+// State returns the state of the connection.
+func (s *Supervisor) State() ConnState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// Run checks the connection and reconnects until stop is closed.
+func (s *Supervisor) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	var clientStop chan struct{}
+	defer func() {
+		if clientStop != nil {
+			close(clientStop)
+		}
+	}()
+	for {
+		if s.State() == ConnDegraded {
+			if clientStop != nil {
+				close(clientStop)
+				clientStop = nil
+			}
+			if !s.reconnect(stop) {
+				return
+			}
+		}
+		if clientStop == nil {
+			clientStop = make(chan struct{})
+			if r, ok := s.conn().(runner); ok {
+				go r.Run(clientStop)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-s.check:
+		}
+		if c := s.conn(); c != nil {
+			if _, err := c.NodeStatus(); err != nil {
+				s.degrade(err)
+			}
+		}
+	}
+}
+
+// Close closes the connection.
+func (s *Supervisor) Close() error {
+	if closer, ok := s.conn().(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// connect dials the node and checks that it answers.
+func (s *Supervisor) connect() (Client, error) {
+	c, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.NodeStatus(); err != nil {
+		closeClient(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+// reconnect dials until connected, doubling the delay between attempts. It
+// returns false if stop was closed first.
+func (s *Supervisor) reconnect(stop <-chan struct{}) bool {
+	delay := s.minDelay
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(delay):
+		}
+		c, err := s.connect()
+		if err == nil {
+			s.setState(c, ConnConnected)
+			return true
+		}
+		delay *= 2
+		if delay > s.maxDelay {
+			delay = s.maxDelay
+		}
+		logger.Warn("node reconnect failed", zap.String("network", s.network), zap.Duration("retry", delay), zap.Error(err))
+	}
+}
+
+// degrade drops the connection, Run reconnects.
+func (s *Supervisor) degrade(err error) {
+	logger.Warn("node connection degraded", zap.String("network", s.network), zap.Error(err))
+	if c := s.setState(nil, ConnDegraded); c != nil {
+		closeClient(c)
+	}
+}
+
+// setState sets the client and state and tells the listeners if the state
+// changed. It returns the previous client.
+func (s *Supervisor) setState(c Client, state ConnState) Client {
+	s.mu.Lock()
+	prev, changed := s.client, s.state != state
+	s.client, s.state = c, state
+	listeners := s.listeners
+	s.mu.Unlock()
+	if !changed {
+		return prev
+	}
+	if state == ConnConnected {
+		logger.Info("node connection restored", zap.String("network", s.network))
+	}
+	for _, l := range listeners {
+		l.ConnStateChanged(s.network, state)
+	}
+	return prev
+}
+
+// conn returns the client, nil while degraded.
+func (s *Supervisor) conn() Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+// call runs fn with the client and asks for a health check if it failed with
+// a connection error.
+func (s *Supervisor) call(fn func(c Client) error) error {
+	c := s.conn()
+	if c == nil {
+		return ErrDegraded
+	}
+	err := fn(c)
+	if isConnectionError(err) {
+		select {
+		case s.check <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+func closeClient(c Client) {
+	if closer, ok := c.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+func (s *Supervisor) NodeStatus() (*apitypes.NodeStatus, error) {
+	var res *apitypes.NodeStatus
+	err := s.call(func(c Client) (err error) {
+		res, err = c.NodeStatus()
+		return err
+	})
+	return res, err
+}
+
+func (s *Supervisor) AccountState(address gosmtypes.Address) (*apitypes.Account, error) {
+	var res *apitypes.Account
+	err := s.call(func(c Client) (err error) {
+		res, err = c.AccountState(address)
+		return err
+	})
+	return res, err
+}
+
+func (s *Supervisor) Transfer(recipient gosmtypes.Address, nonce, amount, gasPrice, gasLimit uint64, key ed25519.PrivateKey) (*apitypes.TransactionState, error) {
+	var res *apitypes.TransactionState
+	err := s.call(func(c Client) (err error) {
+		res, err = c.Transfer(recipient, nonce, amount, gasPrice, gasLimit, key)
+		return err
+	})
+	return res, err
+}
+
+func (s *Supervisor) TransactionState(txId []byte, includeTx bool) (*apitypes.TransactionState, *apitypes.Transaction, error) {
+	var state *apitypes.TransactionState
+	var tx *apitypes.Transaction
+	err := s.call(func(c Client) (err error) {
+		state, tx, err = c.TransactionState(txId, includeTx)
+		return err
+	})
+	return state, tx, err
+}
+
+func (s *Supervisor) GetMeshTransactions(address gosmtypes.Address, offset uint32, maxResults uint32) ([]*apitypes.MeshTransaction, uint32, error) {
+	var txs []*apitypes.MeshTransaction
+	var total uint32
+	err := s.call(func(c Client) (err error) {
+		txs, total, err = c.GetMeshTransactions(address, offset, maxResults)
+		return err
+	})
+	return txs, total, err
+}
+
+func (s *Supervisor) SubscribeTransactionState(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+	var res <-chan *apitypes.TransactionState
+	err := s.call(func(c Client) (err error) {
+		res, err = c.SubscribeTransactionState(ctx, txId)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errUnavailable = status.Error(codes.Unavailable, "connection refused")
+
+type connListener struct {
+	states chan ConnState
+}
+
+func (l *connListener) ConnStateChanged(network string, state ConnState) {
+	l.states <- state
+}
+
+// next returns the next state change or fails the test after a while.
+func (l *connListener) next(t *testing.T) ConnState {
+	t.Helper()
+	select {
+	case state := <-l.states:
+		return state
+	case <-time.After(5 * time.Second):
+		t.Fatal("no connection state change")
+		return ConnConnected
+	}
+}
+
+// testDialer returns the clients in order, failing with errUnavailable for nil entries.
+type testDialer struct {
+	mu      sync.Mutex
+	clients []*fakeClient
+	dials   int
+}
+
+func (d *testDialer) dial() (Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dials++
+	if len(d.clients) == 0 || d.clients[0] == nil {
+		if len(d.clients) > 0 {
+			d.clients = d.clients[1:]
+		}
+		return nil, errUnavailable
+	}
+	c := d.clients[0]
+	d.clients = d.clients[1:]
+	return c, nil
+}
+
+func newTestSupervisor(d *testDialer) (*Supervisor, *connListener) {
+	s := NewSupervisor("testnet", d.dial, time.Hour)
+	s.minDelay, s.maxDelay = time.Millisecond, 4*time.Millisecond
+	l := &connListener{states: make(chan ConnState, 4)}
+	s.AddListener(l)
+	return s, l
+}
+
+func TestSupervisorReconnects(t *testing.T) {
+	first, second := newFakeClient(), newFakeClient()
+	d := &testDialer{clients: []*fakeClient{first, nil, nil, second}}
+	s, l := newTestSupervisor(d)
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Run(stop)
+
+	first.setDown(errUnavailable)
+	if _, err := s.AccountState(testWallet(1).Address); err != errUnavailable {
+		t.Errorf("error = %v, want %v", err, errUnavailable)
+	}
+	if state := l.next(t); state != ConnDegraded {
+		t.Fatalf("state = %v, want degraded", state)
+	}
+	if state := l.next(t); state != ConnConnected {
+		t.Fatalf("state = %v, want connected", state)
+	}
+	if _, err := s.AccountState(testWallet(1).Address); err != nil {
+		t.Errorf("call after reconnecting failed: %v", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dials != 4 {
+		t.Errorf("dialed %v times, want 4", d.dials)
+	}
+}
+
+func TestSupervisorStartsDegraded(t *testing.T) {
+	down := newFakeClient()
+	down.setDown(errUnavailable)
+	d := &testDialer{clients: []*fakeClient{down, newFakeClient()}}
+	s, l := newTestSupervisor(d)
+	if state := l.next(t); state != ConnDegraded {
+		t.Fatalf("state = %v, want degraded", state)
+	}
+	if _, err := s.NodeStatus(); err != ErrDegraded {
+		t.Errorf("error = %v, want %v", err, ErrDegraded)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Run(stop)
+	if state := l.next(t); state != ConnConnected {
+		t.Fatalf("state = %v, want connected", state)
+	}
+	if _, err := s.NodeStatus(); err != nil {
+		t.Errorf("call after connecting failed: %v", err)
+	}
+}
+
+func TestSupervisorIgnoresNodeErrors(t *testing.T) {
+	c := newFakeClient()
+	d := &testDialer{clients: []*fakeClient{c}}
+	s, l := newTestSupervisor(d)
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Run(stop)
+
+	// an error of a working node does not break the connection
+	if _, _, err := s.TransactionState([]byte{0x02}, false); err == nil {
+		t.Fatal("no error for an unknown tx")
+	}
+	select {
+	case state := <-l.states:
+		t.Errorf("state changed to %v", state)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBotDegraded(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+
+	b.ConnStateChanged("testnet", ConnDegraded)
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != ErrDegraded {
+		t.Errorf("error = %v, want %v", err, ErrDegraded)
+	}
+	if n := len(client.sent()); n != 0 {
+		t.Errorf("sent %v transfers while degraded", n)
+	}
+	resp, err := b.Handle(command(faucetStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data.(*FaucetStatusResult).Degraded {
+		t.Error("faucet status is not degraded")
+	}
+
+	b.ConnStateChanged("testnet", ConnConnected)
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Errorf("request after reconnecting failed: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+const (
+	telegramPollTimeout  = 30 * time.Second
+	telegramRetryBackoff = 5 * time.Second
+)
+
+// telegramCommands maps telegram slash commands to bot commands.
+var telegramCommands = map[string]string{
+	"/start":          help,
+	"/help":           help,
+	"/balance":        balance,
+	"/tx_info":        txInfo,
+	"/faucet_status":  faucetStatus,
+	"/faucet_address": faucetAddr,
+	"/dump_txs":       dumpTxs,
+	"/my_tier":        myTier,
+	"/recent_payouts": recentPays,
+}
+
+// TelegramFrontend serves bot commands sent to a telegram bot.
+// Updates are received by long polling the Bot API at apiURL, which can point
+// to a local fake server for testing.
+type TelegramFrontend struct {
+	token   string
+	apiURL  string
+	client  *http.Client
+	handler Handler
+	offset  int64
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func NewTelegramFrontend(cfg BaseConfig) *TelegramFrontend {
+	apiURL := cfg.TelegramAPIURL
+	if apiURL == "" {
+		apiURL = defaultTelegramAPIURL
+	}
+	return &TelegramFrontend{
+		token:  cfg.TelegramToken,
+		apiURL: strings.TrimRight(apiURL, "/"),
+		client: &http.Client{Timeout: telegramPollTimeout + 10*time.Second},
+	}
+}
+
+func (t *TelegramFrontend) Name() string {
+	return "telegram"
+}
+
+type tgUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+type tgChat struct {
+	ID int64 `json:"id"`
+}
+
+type tgMessage struct {
+	MessageID int64   `json:"message_id"`
+	From      *tgUser `json:"from"`
+	Chat      tgChat  `json:"chat"`
+	Text      string  `json:"text"`
+}
+
+type tgUpdate struct {
+	UpdateID int64      `json:"update_id"`
+	Message  *tgMessage `json:"message"`
+}
+
+type tgResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// Start checks the bot token and starts polling for updates in the background.
+func (t *TelegramFrontend) Start(h Handler) error {
+	t.handler = h
+	ctx, cancel := context.WithCancel(context.Background())
+	var me tgUser
+	if err := t.call(ctx, "getMe", struct{}{}, &me); err != nil {
+		cancel()
+		return fmt.Errorf("telegram bot login failed: %v", err)
+	}
+	logger.Info("telegram bot logged in", zap.String("username", me.Username))
+
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go t.poll(ctx)
+	return nil
+}
+
+func (t *TelegramFrontend) Close() error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+	<-t.done
+	return nil
+}
+
+func (t *TelegramFrontend) poll(ctx context.Context) {
+	defer close(t.done)
+	for {
+		var updates []tgUpdate
+		params := map[string]interface{}{
+			"offset":          t.offset,
+			"timeout":         int(telegramPollTimeout.Seconds()),
+			"allowed_updates": []string{"message"},
+		}
+		err := t.call(ctx, "getUpdates", params, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn("telegram getUpdates failed", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(telegramRetryBackoff):
+			}
+			continue
+		}
+		for _, u := range updates {
+			t.offset = u.UpdateID + 1
+			if u.Message != nil && u.Message.From != nil {
+				t.onMessage(ctx, u.Message)
+			}
+		}
+	}
+}
+
+func (t *TelegramFrontend) onMessage(ctx context.Context, m *tgMessage) {
+	args := telegramArgs(m.Text)
+	if len(args) == 0 {
+		return
+	}
+	name := m.From.Username
+	if name == "" {
+		name = m.From.FirstName
+	}
+	req := &Request{
+		Frontend:      t.Name(),
+		RequesterID:   "tg:" + strconv.FormatInt(m.From.ID, 10),
+		RequesterName: name,
+		ChannelID:     strconv.FormatInt(m.Chat.ID, 10),
+		MessageID:     strconv.FormatInt(m.MessageID, 10),
+		Args:          args,
+	}
+
+	resp, err := t.handler.Handle(req)
+	if err == ErrUnknownCommand {
+		return
+	}
+	text := ""
+	if err != nil {
+		// only fund request failures are reported back to the requester
+		if !isTransferRequest(req) {
+			return
+		}
+		text = err.Error()
+	} else {
+		text = resp.Text
+	}
+	if err := t.send(ctx, m.Chat.ID, text); err != nil {
+		req.Logger().Warn("telegram sendMessage failed", zap.Error(err))
+	}
+}
+
+// telegramArgs converts a telegram message to bot command args, "/balance@faucet_bot 0x1"
+// becomes ["$balance", "0x1"]. Addresses are passed through as fund requests.
+func telegramArgs(text string) []string {
+	args := strings.Fields(text)
+	if len(args) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(args[0], "/") {
+		return args
+	}
+	cmd := strings.ToLower(strings.SplitN(args[0], "@", 2)[0])
+	mapped, ok := telegramCommands[cmd]
+	if !ok {
+		return nil
+	}
+	args[0] = mapped
+	return args
+}
+
+func (t *TelegramFrontend) send(ctx context.Context, chatID int64, text string) error {
+	params := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	return t.call(ctx, "sendMessage", params, nil)
+}
+
+func (t *TelegramFrontend) NotifyChannel(channelID string, msg string) error {
+	chatID, err := strconv.ParseInt(channelID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram chat id %v", channelID)
+	}
+	return t.send(context.Background(), chatID, msg)
+}
+
+// NotifyUser messages a user directly, in telegram a private chat id is the user id.
+func (t *TelegramFrontend) NotifyUser(userID string, msg string) error {
+	return t.NotifyChannel(strings.TrimPrefix(userID, "tg:"), msg)
+}
+
+// TxUpdated tells the requester the final state of their payout.
+func (t *TelegramFrontend) TxUpdated(u *TxUpdate) {
+	if u.Request == nil || u.Request.Frontend != t.Name() || !u.Final {
+		return
+	}
+	if err := t.NotifyChannel(u.Request.ChannelID, u.Text()); err != nil {
+		u.Request.Logger().Warn("failed to send payout state", zap.Error(err))
+	}
+}
+
+// call invokes a Bot API method and decodes its result into result if not nil.
+func (t *TelegramFrontend) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%v/bot%v/%v", t.apiURL, t.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := t.client.Do(req)
+	if err != nil {
+		// the url contains the token, do not leak it in errors
+		return fmt.Errorf("%v request failed", method)
+	}
+	defer httpResp.Body.Close()
+
+	var resp tgResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("%v: malformed response %v", method, err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("%v: %v", method, resp.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTelegram is a Bot API server delivering queued updates to getUpdates and
+// recording the messages sent.
+type fakeTelegram struct {
+	t      *testing.T
+	mu     sync.Mutex
+	queued []tgUpdate
+	nextID int64
+	sent   chan tgSent
+}
+
+type tgSent struct {
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func newFakeTelegram(t *testing.T) (*fakeTelegram, *httptest.Server) {
+	f := &fakeTelegram{t: t, sent: make(chan tgSent, 10)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeTelegram) message(userID int64, username, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.queued = append(f.queued, tgUpdate{
+		UpdateID: f.nextID,
+		Message: &tgMessage{
+			MessageID: f.nextID,
+			From:      &tgUser{ID: userID, Username: username},
+			Chat:      tgChat{ID: userID},
+			Text:      text,
+		},
+	})
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var result interface{} = true
+	switch {
+	case r.URL.Path == "/bottest-token/getMe":
+		result = tgUser{ID: 1, Username: "faucet_bot"}
+	case r.URL.Path == "/bottest-token/getUpdates":
+		var params struct {
+			Offset int64 `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		// long poll briefly so the frontend does not spin
+		var updates []tgUpdate
+		for deadline := time.Now().Add(50 * time.Millisecond); time.Now().Before(deadline) && r.Context().Err() == nil; {
+			f.mu.Lock()
+			for _, u := range f.queued {
+				if u.UpdateID >= params.Offset {
+					updates = append(updates, u)
+				}
+			}
+			f.mu.Unlock()
+			if len(updates) > 0 {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		result = updates
+	case r.URL.Path == "/bottest-token/sendMessage":
+		var msg tgSent
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			f.t.Errorf("sendMessage body: %v", err)
+		}
+		f.sent <- msg
+	default:
+		json.NewEncoder(w).Encode(tgResponse{OK: false, Description: "Not Found"})
+		return
+	}
+	raw, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(tgResponse{OK: true, Result: raw})
+}
+
+func (f *fakeTelegram) reply(t *testing.T) tgSent {
+	t.Helper()
+	select {
+	case msg := <-f.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply sent")
+		return tgSent{}
+	}
+}
+
+// recordingHandler passes requests to h and keeps them.
+type recordingHandler struct {
+	h    Handler
+	mu   sync.Mutex
+	reqs []*Request
+}
+
+func (r *recordingHandler) Handle(req *Request) (*Response, error) {
+	r.mu.Lock()
+	r.reqs = append(r.reqs, req)
+	r.mu.Unlock()
+	return r.h.Handle(req)
+}
+
+func (r *recordingHandler) last() *Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reqs[len(r.reqs)-1]
+}
+
+func TestTelegramArgs(t *testing.T) {
+	for text, want := range map[string][]string{
+		"/balance@faucet_bot 0x1": {balance, "0x1"},
+		"/START":                  {help},
+		"/recent_payouts":         {recentPays},
+		testAddress:               {testAddress},
+		"/nope":                   nil,
+		"  ":                      nil,
+	} {
+		if args := telegramArgs(text); !reflect.DeepEqual(args, want) {
+			t.Errorf("telegramArgs(%q) = %q, want %q", text, args, want)
+		}
+	}
+}
+
+func TestTelegramFrontend(t *testing.T) {
+	fake, srv := newFakeTelegram(t)
+	b, _, _ := newTestBot(t, testConfig())
+	h := &recordingHandler{h: b}
+
+	cfg := testConfig()
+	cfg.TelegramToken = "test-token"
+	cfg.TelegramAPIURL = srv.URL + "/"
+	tg := NewTelegramFrontend(cfg)
+	if err := tg.Start(h); err != nil {
+		t.Fatal(err)
+	}
+	defer tg.Close()
+
+	fake.message(42, "alice", "/help@faucet_bot")
+	if reply := fake.reply(t); reply.ChatID != 42 || !strings.Contains(reply.Text, "$balance") {
+		t.Errorf("help reply = %+v", reply)
+	}
+	if req := h.last(); req.Frontend != "telegram" || !reflect.DeepEqual(req.Args, []string{help}) {
+		t.Errorf("help request = %+v", req)
+	}
+
+	fake.message(42, "alice", testAddress)
+	if reply := fake.reply(t); reply.ChatID != 42 || reply.Text == "" {
+		t.Errorf("fund reply = %+v", reply)
+	}
+	if req := h.last(); req.RequesterID != "tg:42" || req.RequesterName != "alice" {
+		t.Errorf("fund request from %v (%v), want tg:42 (alice)", req.RequesterID, req.RequesterName)
+	}
+	// the cooldown is kept under the telegram user id
+	if _, ok := b.State().Backoff["user:tg:42"]; !ok {
+		t.Errorf("cooldowns = %v, want user:tg:42", b.State().Backoff)
+	}
+
+	// fund request failures are sent back to the requester
+	fake.message(42, "alice", testAddress)
+	if reply := fake.reply(t); !strings.Contains(reply.Text, "too soon") {
+		t.Errorf("cooldown reply = %+v", reply)
+	}
+
+	// unknown commands are ignored, the next reply answers the following message
+	fake.message(7, "bob", "/nope")
+	fake.message(7, "bob", "/faucet_address")
+	if reply := fake.reply(t); reply.ChatID != 7 || !strings.Contains(reply.Text, testWallet(1).Address.String()) {
+		t.Errorf("faucet address reply = %+v", reply)
+	}
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+)
+
+const defaultTierName = "default"
+
+const dailyCapWindow = 24 * time.Hour
+
+// TierConfig is a payout tier granted to members holding any of Roles.
+type TierConfig struct {
+	Name            string        `mapstructure:"name"`
+	Roles           []string      `mapstructure:"roles"`
+	TransferAmount  uint64        `mapstructure:"transfer-amount"`
+	RequestCoolDown time.Duration `mapstructure:"cooldown"`
+	// DailyCap is the max amount a member can receive in 24 hours, 0 means no cap.
+	DailyCap uint64 `mapstructure:"daily-cap"`
+}
+
+type payout struct {
+	at     time.Time
+	amount uint64
+}
+
+// defaultTier returns the tier used for requesters without a matching role.
+func (c BaseConfig) defaultTier() TierConfig {
+	return TierConfig{
+		Name:            defaultTierName,
+		TransferAmount:  c.TransferAmount,
+		RequestCoolDown: c.RequestCoolDown,
+		DailyCap:        c.DailyCap,
+	}
+}
+
+// resolveTier returns the first configured tier matching one of roles.
+// Tiers are checked in config order so more privileged tiers should come first.
+func (c BaseConfig) resolveTier(roles []string) TierConfig {
+	for _, tier := range c.Tiers {
+		for _, tierRole := range tier.Roles {
+			for _, role := range roles {
+				if role == tierRole {
+					return tier
+				}
+			}
+		}
+	}
+	return c.defaultTier()
+}
+
+// dailyTotal returns the amount paid to requester in the last 24 hours.
+// Must be called with b.mu held.
+func (b *botBackend) dailyTotal(requesterID string, now time.Time) uint64 {
+	var total uint64
+	recent := b.payouts[requesterID][:0]
+	for _, p := range b.payouts[requesterID] {
+		if now.Sub(p.at) < dailyCapWindow {
+			recent = append(recent, p)
+			total += p.amount
+		}
+	}
+	b.payouts[requesterID] = recent
+	return total
+}
+
+func (b *botBackend) getMyTier(req *Request) (*Response, error) {
+	tier := b.config().withGuild(req.Guild).resolveTier(req.Roles)
+
+	b.mu.Lock()
+	now := b.now()
+	used := b.dailyTotal(req.RequesterID, now)
+	next, hasNext := b.backoff[userBackoffKey(req.RequesterID)]
+	b.mu.Unlock()
+
+	res := &TierResult{
+		Tier:      tier.Name,
+		Amount:    tier.TransferAmount,
+		CoolDown:  tier.RequestCoolDown,
+		DailyCap:  tier.DailyCap,
+		DailyUsed: used,
+	}
+	capStr := "none"
+	if tier.DailyCap > 0 {
+		capStr = fmt.Sprintf("%v (used %v)", tier.DailyCap, used)
+	}
+	msg := fmt.Sprintf("tier: %v\namount: %v\ncooldown: %v\ndaily cap: %v", tier.Name, tier.TransferAmount, tier.RequestCoolDown, capStr)
+	if hasNext && now.Before(next) {
+		res.NextRequest = next
+		msg += fmt.Sprintf("\nnext request in: %v", next.Sub(now).Round(time.Second))
+	}
+	return &Response{Command: myTier, Text: msg, Data: res}, nil
+}
+
+func userBackoffKey(userID string) string {
+	return "user:" + userID
+}
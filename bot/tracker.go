@@ -0,0 +1,285 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTxPollInterval = 10 * time.Second
+	// defaultTxTrackTimeout matches the tracking time announced by $help
+	defaultTxTrackTimeout = 15 * time.Minute
+
+	minStreamReconnect = time.Second
+	maxStreamReconnect = time.Minute
+)
+
+// TxUpdate is a state change of a tracked payout transaction.
+type TxUpdate struct {
+	Network string
+	TxID    string
+	State   apitypes.TransactionState_TransactionState
+	// Final is set on the last update of a tx, once it is processed, rejected
+	// or was not confirmed in time
+	Final    bool
+	TimedOut bool
+	Request  *Request
+	Payout   TransferResult
+}
+
+// Confirmed returns true if the tx was processed.
+func (u *TxUpdate) Confirmed() bool {
+	return u.State == apitypes.TransactionState_TRANSACTION_STATE_PROCESSED
+}
+
+// StateText returns the state for display.
+func (u *TxUpdate) StateText() string {
+	if u.TimedOut {
+		return "Not confirmed in time"
+	}
+	return transactionStateDisStringsMap[int32(u.State)]
+}
+
+// Text returns the update as a message for the requester.
+func (u *TxUpdate) Text() string {
+	switch {
+	case !u.Final:
+		return fmt.Sprintf("💸 transaction %v of %v to %v: %v", u.TxID, u.Payout.Amount, u.Payout.Address, u.StateText())
+	case u.Confirmed():
+		return fmt.Sprintf("✅ transaction %v of %v to %v confirmed", u.TxID, u.Payout.Amount, u.Payout.Address)
+	default:
+		return fmt.Sprintf("🚫 transaction %v of %v to %v failed: %v. You need to make another request", u.TxID, u.Payout.Amount, u.Payout.Address, u.StateText())
+	}
+}
+
+// TxListener is notified of the state changes of tracked transactions.
+type TxListener interface {
+	TxUpdated(u *TxUpdate)
+}
+
+// TxTracker follows payout transactions until they are final and pushes their
+// state changes to listeners. States are received from the node's transaction
+// state stream, which is reconnected when it fails. While the stream is down or
+// not supported by the node the state is polled instead.
+type TxTracker struct {
+	backend      Client
+	network      string
+	pollInterval time.Duration
+	timeout      time.Duration
+
+	mu        sync.RWMutex
+	listeners []TxListener
+	// pending are the txs followed until they are final, by id
+	pending map[string]*TxUpdate
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewTxTracker(backend Client, cfg BaseConfig) *TxTracker {
+	t := &TxTracker{
+		backend:      backend,
+		network:      cfg.Network,
+		pollInterval: cfg.TxPollInterval,
+		timeout:      cfg.TxTrackTimeout,
+		pending:      make(map[string]*TxUpdate),
+	}
+	if t.pollInterval <= 0 {
+		t.pollInterval = defaultTxPollInterval
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultTxTrackTimeout
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	return t
+}
+
+// AddListener registers l for the updates of all tracked transactions.
+func (t *TxTracker) AddListener(l TxListener) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listeners = append(t.listeners, l)
+}
+
+// Track follows the tx submitted for req in state until it is final.
+func (t *TxTracker) Track(txID []byte, state apitypes.TransactionState_TransactionState, req *Request, payout TransferResult) {
+	if t == nil {
+		return
+	}
+	u := &TxUpdate{
+		Network: t.network,
+		TxID:    "0x" + Bytes2Hex(txID),
+		State:   state,
+		Final:   isFinalTxState(state),
+		Request: req,
+		Payout:  payout,
+	}
+	t.notify(u)
+	if u.Final {
+		return
+	}
+
+	t.mu.Lock()
+	t.pending[u.TxID] = u
+	t.mu.Unlock()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.follow(txID, u)
+	}()
+}
+
+// Pending returns the txs not final yet, to be resumed after a restart.
+func (t *TxTracker) Pending() []TrackedTx {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	txs := make([]TrackedTx, 0, len(t.pending))
+	for _, u := range t.pending {
+		txs = append(txs, TrackedTx{TxID: u.TxID, State: int32(u.State), Request: u.Request, Payout: u.Payout})
+	}
+	return txs
+}
+
+// Resume tracks txs returned by Pending.
+func (t *TxTracker) Resume(txs []TrackedTx) {
+	for _, tx := range txs {
+		t.Track(FromHex(tx.TxID), apitypes.TransactionState_TransactionState(tx.State), tx.Request, tx.Payout)
+	}
+}
+
+// Close stops tracking and waits for the trackers to return.
+func (t *TxTracker) Close() {
+	if t == nil {
+		return
+	}
+	t.cancel()
+	t.wg.Wait()
+}
+
+func (t *TxTracker) follow(txID []byte, u *TxUpdate) {
+	ctx, cancel := context.WithTimeout(t.ctx, t.timeout)
+	defer cancel()
+	defer func() {
+		// txs still followed when the tracker is closed stay pending
+		t.mu.Lock()
+		if u.Final || t.ctx.Err() == nil {
+			delete(t.pending, u.TxID)
+		}
+		t.mu.Unlock()
+	}()
+
+	reconnect := minStreamReconnect
+	for {
+		states, err := t.backend.SubscribeTransactionState(ctx, txID)
+		if err == nil {
+			received := false
+			for st := range states {
+				received = true
+				if t.update(u, st.GetState()) {
+					return
+				}
+			}
+			if received {
+				reconnect = minStreamReconnect
+			}
+		} else if err == ErrStreamUnsupported {
+			// no point in retrying, poll until the tx is final
+			reconnect = t.timeout
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		// the stream is down or not supported, poll until it is retried
+		if t.poll(ctx, txID, u, reconnect) {
+			return
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if reconnect *= 2; reconnect > maxStreamReconnect {
+			reconnect = maxStreamReconnect
+		}
+	}
+
+	// stopped by Close, the tx is not final yet
+	if t.ctx.Err() != nil {
+		return
+	}
+	timedOut := *u
+	timedOut.Final, timedOut.TimedOut = true, true
+	t.notify(&timedOut)
+}
+
+// poll polls the tx state for at least d, returns true once the tx is final.
+func (t *TxTracker) poll(ctx context.Context, txID []byte, u *TxUpdate, d time.Duration) bool {
+	if d < t.pollInterval {
+		d = t.pollInterval
+	}
+	deadline := time.NewTimer(d)
+	defer deadline.Stop()
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+			// the wallet backend panics if the tx is not included
+			st, _, err := t.backend.TransactionState(txID, true)
+			if err != nil {
+				continue
+			}
+			if t.update(u, st.GetState()) {
+				return true
+			}
+		}
+	}
+}
+
+// update notifies a state change and returns true if the new state is final.
+func (t *TxTracker) update(u *TxUpdate, state apitypes.TransactionState_TransactionState) bool {
+	if state == apitypes.TransactionState_TRANSACTION_STATE_UNSPECIFIED || state == u.State {
+		return false
+	}
+	t.mu.Lock()
+	u.State = state
+	u.Final = isFinalTxState(state)
+	changed := *u
+	t.mu.Unlock()
+	t.notify(&changed)
+	return changed.Final
+}
+
+func (t *TxTracker) notify(u *TxUpdate) {
+	log := logger
+	if u.Request != nil {
+		log = u.Request.Logger()
+	}
+	log.Info("payout state changed", zap.String("network", u.Network), zap.String("tx_id", u.TxID), zap.String("state", u.StateText()), zap.Bool("final", u.Final))
+
+	t.mu.RLock()
+	listeners := append([]TxListener{}, t.listeners...)
+	t.mu.RUnlock()
+	for _, l := range listeners {
+		l.TxUpdated(u)
+	}
+}
+
+// isFinalTxState returns true for states a tx does not leave anymore.
+func isFinalTxState(state apitypes.TransactionState_TransactionState) bool {
+	switch state {
+	case apitypes.TransactionState_TRANSACTION_STATE_PROCESSED,
+		apitypes.TransactionState_TRANSACTION_STATE_REJECTED,
+		apitypes.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+		apitypes.TransactionState_TRANSACTION_STATE_CONFLICTING:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	apitypes "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	txMempool   = apitypes.TransactionState_TRANSACTION_STATE_MEMPOOL
+	txMesh      = apitypes.TransactionState_TRANSACTION_STATE_MESH
+	txProcessed = apitypes.TransactionState_TRANSACTION_STATE_PROCESSED
+	txRejected  = apitypes.TransactionState_TRANSACTION_STATE_REJECTED
+)
+
+type recordingListener struct {
+	updates chan TxUpdate
+}
+
+func newRecordingListener() *recordingListener {
+	return &recordingListener{updates: make(chan TxUpdate, 16)}
+}
+
+func (l *recordingListener) TxUpdated(u *TxUpdate) {
+	l.updates <- *u
+}
+
+// next returns the next update or fails the test after a while.
+func (l *recordingListener) next(t *testing.T) TxUpdate {
+	t.Helper()
+	select {
+	case u := <-l.updates:
+		return u
+	case <-time.After(5 * time.Second):
+		t.Fatal("no tx update")
+		return TxUpdate{}
+	}
+}
+
+func newTestTracker(client *fakeClient) (*TxTracker, *recordingListener) {
+	cfg := testConfig()
+	cfg.TxPollInterval = 10 * time.Millisecond
+	tracker := NewTxTracker(client, cfg)
+	l := newRecordingListener()
+	tracker.AddListener(l)
+	return tracker, l
+}
+
+func TestTrackerStream(t *testing.T) {
+	client := newFakeClient()
+	streamed := make(chan *apitypes.TransactionState)
+	client.subscribe = func(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+		return streamed, nil
+	}
+	tracker, l := newTestTracker(client)
+	defer tracker.Close()
+
+	tracker.Track([]byte{0x01}, txMempool, command("0x01"), TransferResult{Amount: 100})
+	if u := l.next(t); u.State != txMempool || u.Final || u.TxID != "0x01" || u.Network != "testnet" {
+		t.Errorf("unexpected first update %+v", u)
+	}
+	streamed <- &apitypes.TransactionState{State: txMesh}
+	if u := l.next(t); u.State != txMesh || u.Final {
+		t.Errorf("unexpected mesh update %+v", u)
+	}
+	// repeated states are not notified again
+	streamed <- &apitypes.TransactionState{State: txMesh}
+	streamed <- &apitypes.TransactionState{State: txProcessed}
+	if u := l.next(t); !u.Final || !u.Confirmed() {
+		t.Errorf("unexpected final update %+v", u)
+	}
+	if n := client.pollCount(); n != 0 {
+		t.Errorf("polled %v times while streaming", n)
+	}
+}
+
+func TestTrackerPollsWithoutStream(t *testing.T) {
+	client := newFakeClient()
+	tracker, l := newTestTracker(client)
+	defer tracker.Close()
+
+	tracker.Track([]byte{0x01}, txMempool, command("0x01"), TransferResult{})
+	l.next(t)
+	client.setState([]byte{0x01}, txRejected)
+	if u := l.next(t); !u.Final || u.Confirmed() || u.State != txRejected {
+		t.Errorf("unexpected final update %+v", u)
+	}
+}
+
+func TestTrackerReconnects(t *testing.T) {
+	client := newFakeClient()
+	var mu sync.Mutex
+	subscriptions := 0
+	client.subscribe = func(ctx context.Context, txId []byte) (<-chan *apitypes.TransactionState, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		subscriptions++
+		states := make(chan *apitypes.TransactionState, 1)
+		switch subscriptions {
+		case 1:
+			// the stream breaks right away
+			close(states)
+		case 2:
+			return nil, errors.New("connection refused")
+		default:
+			states <- &apitypes.TransactionState{State: txProcessed}
+		}
+		return states, nil
+	}
+	tracker, l := newTestTracker(client)
+	defer tracker.Close()
+
+	tracker.Track([]byte{0x01}, txMempool, command("0x01"), TransferResult{})
+	l.next(t)
+	if u := l.next(t); !u.Confirmed() {
+		t.Errorf("unexpected final update %+v", u)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if subscriptions != 3 {
+		t.Errorf("subscribed %v times, want 3", subscriptions)
+	}
+}
+
+func TestTrackerTimeout(t *testing.T) {
+	client := newFakeClient()
+	cfg := testConfig()
+	cfg.TxPollInterval = 10 * time.Millisecond
+	cfg.TxTrackTimeout = 50 * time.Millisecond
+	tracker := NewTxTracker(client, cfg)
+	defer tracker.Close()
+	l := newRecordingListener()
+	tracker.AddListener(l)
+
+	tracker.Track([]byte{0x01}, txMempool, command("0x01"), TransferResult{})
+	l.next(t)
+	u := l.next(t)
+	if !u.Final || !u.TimedOut || u.Confirmed() || u.State != txMempool {
+		t.Errorf("unexpected timeout update %+v", u)
+	}
+}
+
+func TestTrackerClose(t *testing.T) {
+	client := newFakeClient()
+	tracker, l := newTestTracker(client)
+
+	tracker.Track([]byte{0x01}, txMempool, command("0x01"), TransferResult{})
+	l.next(t)
+	tracker.Close()
+	select {
+	case u := <-l.updates:
+		t.Errorf("update after close %+v", u)
+	default:
+	}
+}
+
+func TestBotTracksPayouts(t *testing.T) {
+	b, client, _ := newTestBot(t, testConfig())
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := OpenAuditLog(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	b.SetAuditLog(auditLog)
+	tracker, l := newTestTracker(client)
+	defer tracker.Close()
+	b.SetTracker(tracker)
+
+	if _, err := b.Handle(transferRequest("alice", testAddress)); err != nil {
+		t.Fatal(err)
+	}
+	l.next(t)
+	client.setState([]byte{0x01}, txProcessed)
+	l.next(t)
+
+	resp, err := b.Handle(command(recentPays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := resp.Data.(*RecentPayoutsResult).Payouts[0]; p.State != transactionStateDisStringsMap[int32(txProcessed)] {
+		t.Errorf("recent payout state = %v", p.State)
+	}
+	resp, err = b.Handle(command(faucetStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := resp.Data.(*FaucetStatusResult); res.Confirmed != 1 || res.Failed != 0 {
+		t.Errorf("payouts confirmed %v failed %v, want 1 and 0", res.Confirmed, res.Failed)
+	}
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	last, err := VerifyAuditLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 2 || last.Decision != AuditConfirmed || last.TxID != "0x01" || last.Requester != "alice" {
+		t.Errorf("unexpected last audit entry %+v", last)
+	}
+}
@@ -1,9 +1,9 @@
 package bot
 
 import (
+	"bytes"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"github.com/spacemeshos/ed25519"
 )
 
@@ -37,15 +37,15 @@ func FromHex(s string) []byte {
 // NewEdSignerFromBuffer builds a signer from a private key as byte buffer
 func NewPrivateKeyFromBuffer(buff []byte) (ed25519.PrivateKey, error) {
 	if len(buff) != ed25519.PrivateKeySize {
-		fmt.Println("cannot parse public key")
 		return nil, errors.New("privat key length too small")
 	}
 
 	keyPair := ed25519.NewKeyFromSeed(buff[:32])
-	/*if !bytes.Equal(keyPair[32:], sgn.pubKey) {
-		log.Error("Public key and private key does not match")
-		return nil, errors.New("private and public does not match")
-	}*/
+	// a zero public half pads a bare seed, any other has to match the seed
+	pub := buff[32:]
+	if !bytes.Equal(pub, make([]byte, len(pub))) && !bytes.Equal(keyPair[32:], pub) {
+		return nil, errors.New("private and public key do not match")
+	}
 
 	return keyPair, nil
 }
\ No newline at end of file
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"bytes"
+	"github.com/spacemeshos/ed25519"
+	"testing"
+)
+
+func TestFromHex(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []byte
+	}{
+		{"0x0102", []byte{0x01, 0x02}},
+		{"0X0a0B", []byte{0x0a, 0x0b}},
+		{"0102", []byte{0x01, 0x02}},
+		// odd length strings are zero padded
+		{"0xabc", []byte{0x0a, 0xbc}},
+		{"1", []byte{0x01}},
+		{"", []byte{}},
+		{"0x", []byte{}},
+		// invalid hex decodes to nothing
+		{"0xzz", []byte{}},
+		{"hello", []byte{}},
+	} {
+		if got := FromHex(tc.in); !bytes.Equal(got, tc.want) {
+			t.Errorf("FromHex(%q) = %x, want %x", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestHexRoundTrip(t *testing.T) {
+	b := []byte{0x00, 0xff, 0x10, 0x7a}
+	if got := Hex2Bytes(Bytes2Hex(b)); !bytes.Equal(got, b) {
+		t.Errorf("round trip of %x = %x", b, got)
+	}
+	if got := FromHex("0x" + Bytes2Hex(b)); !bytes.Equal(got, b) {
+		t.Errorf("FromHex of prefixed %x = %x", b, got)
+	}
+}
+
+func TestNewPrivateKeyFromBuffer(t *testing.T) {
+	for _, size := range []int{0, 32, ed25519.PrivateKeySize - 1, ed25519.PrivateKeySize + 1} {
+		if _, err := NewPrivateKeyFromBuffer(make([]byte, size)); err == nil {
+			t.Errorf("no error for a %v byte buffer", size)
+		}
+	}
+
+	seed := bytes.Repeat([]byte{0x42}, 32)
+	want := ed25519.NewKeyFromSeed(seed)
+	key, err := NewPrivateKeyFromBuffer(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, want) {
+		t.Errorf("key = %x, want %x", key, want)
+	}
+
+	// a zero public half pads a bare seed, the key is derived from the seed
+	buf := append(append([]byte{}, seed...), make([]byte, 32)...)
+	key, err = NewPrivateKeyFromBuffer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, want) {
+		t.Errorf("key from seed = %x, want %x", key, want)
+	}
+
+	key, err = NewPrivateKeyFromBuffer(FromHex("0x" + Bytes2Hex(want)))
+	if err != nil || !bytes.Equal(key, want) {
+		t.Errorf("key from hex = %x, %v, want %x", key, err, want)
+	}
+
+	// a public half not matching the seed is rejected
+	copy(buf[32:], ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x43}, 32))[32:])
+	if _, err := NewPrivateKeyFromBuffer(buf); err == nil {
+		t.Error("no error for a public key not matching the private key")
+	}
+}
@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/tyler-smith/go-bip39"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"strings"
+	"time"
+)
+
+// ConfigError lists every problem found by Validate.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// configCheck collects the problems of a config.
+type configCheck struct {
+	problems []string
+}
+
+func (c *configCheck) failf(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the config of every network, the keys derived from it and
+// that a frontend token is set. It returns a *ConfigError listing all problems
+// found, nil if there are none.
+func (c BaseConfig) Validate() error {
+	return c.validate(true)
+}
+
+// ValidateConsole is Validate for the console, which needs no frontend token.
+func (c BaseConfig) ValidateConsole() error {
+	return c.validate(false)
+}
+
+func (c BaseConfig) validate(frontends bool) error {
+	check := &configCheck{}
+	if frontends && c.BotToken == "" && c.TelegramToken == "" {
+		check.failf("no frontend token configured, set token for discord or telegram-token for telegram")
+	}
+	c.validateGlobal(check)
+
+	configs := c.NetworkConfigs()
+	names := make(map[string]bool)
+	channels := make(map[string]string)
+	for i, n := range configs {
+		prefix := ""
+		if len(configs) > 1 {
+			prefix = fmt.Sprintf("network %q: ", n.Network)
+		}
+		if i > 0 && n.Network == "" {
+			check.failf("networks entry %v has no name, set name", i)
+		} else if names[n.Network] {
+			check.failf("%snetwork name is used more than once, every network needs its own name", prefix)
+		}
+		names[n.Network] = true
+		for _, ch := range n.NetworkChannels {
+			if other, ok := channels[ch]; ok && other != n.Network {
+				check.failf("%schannel %v is also bound to network %q, bind it to one network", prefix, ch, other)
+			}
+			channels[ch] = n.Network
+		}
+		n.validateNetwork(check, prefix)
+	}
+
+	if len(check.problems) > 0 {
+		return &ConfigError{Problems: check.problems}
+	}
+	return nil
+}
+
+// validateGlobal checks the settings shared by all networks.
+func (c BaseConfig) validateGlobal(check *configCheck) {
+	if c.LogLevel != "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			check.failf("log-level %q is unknown, use debug, info, warn or error", c.LogLevel)
+		}
+	}
+	if _, ok := helpTexts[c.Language]; c.Language != "" && !ok {
+		check.failf("language %q is not supported, use one of %v", c.Language, strings.Join(languages(), ", "))
+	}
+	if c.WebEnabled && c.HTTPListen == "" {
+		check.failf("web-enabled needs http-listen, the web page is served on the http api address")
+	}
+	if c.BalanceWarning > 0 && c.BalanceCritical > c.BalanceWarning {
+		check.failf("balance-critical %v is above balance-warning %v, the critical threshold has to be the lower one", c.BalanceCritical, c.BalanceWarning)
+	}
+	if c.SimFailRate < 0 || c.SimFailRate > 1 {
+		check.failf("sim-fail-rate %v is not a probability, use a value from 0 to 1", c.SimFailRate)
+	}
+	if c.HTTPListen != "" {
+		checkListenAddr(check, "http-listen", c.HTTPListen)
+	}
+	if c.HTTPProxyHops < 0 {
+		check.failf("http-proxy-hops %v is negative", c.HTTPProxyHops)
+	} else if c.HTTPForwardedHeader != "" && c.HTTPProxyHops == 0 {
+		check.failf("http-forwarded-header is ignored without http-proxy-hops, set the number of proxies in front of the api")
+	}
+	if c.MetricsListen != "" {
+		checkListenAddr(check, "metrics-listen", c.MetricsListen)
+	}
+
+	durations := []struct {
+		key string
+		d   time.Duration
+	}{
+		{"node-check-interval", c.NodeCheckInterval},
+		{"tx-poll-interval", c.TxPollInterval},
+		{"tx-track-timeout", c.TxTrackTimeout},
+		{"shutdown-timeout", c.ShutdownTimeout},
+		{"balance-check-interval", c.BalanceCheckInterval},
+		{"refill-check-interval", c.RefillCheckInterval},
+		{"sim-layer-duration", c.SimLayerDuration},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			check.failf("%v %v is negative", d.key, d.d)
+		}
+	}
+
+	for i, tier := range c.Tiers {
+		name := tier.Name
+		if name == "" {
+			name = fmt.Sprint(i)
+			check.failf("tier %v has no name, set name", i)
+		}
+		if len(tier.Roles) == 0 {
+			check.failf("tier %v has no roles, set the role IDs paid by the tier", name)
+		}
+		if tier.TransferAmount == 0 {
+			check.failf("tier %v has transfer-amount 0, set the amount sent per request", name)
+		}
+		if tier.RequestCoolDown <= 0 {
+			check.failf("tier %v has no cooldown, set how long members wait between requests", name)
+		}
+	}
+}
+
+// validateNetwork checks the node, payout and key settings of a network.
+func (c BaseConfig) validateNetwork(check *configCheck, prefix string) {
+	if c.TransferAmount == 0 {
+		check.failf("%stransfer-amount is 0, set the amount sent per request", prefix)
+	}
+	if c.RequestCoolDown <= 0 {
+		check.failf("%scooldown is %v, set how long requesters wait between requests, e.g. \"3h\"", prefix, c.RequestCoolDown)
+	}
+
+	if !c.Simulate {
+		servers := c.Servers
+		if len(servers) == 0 {
+			servers = []string{c.Server}
+		}
+		for _, s := range servers {
+			if s == "" {
+				check.failf("%sno node configured, set server to the host:port of a node api, or set simulate", prefix)
+				continue
+			}
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				check.failf("%sserver %q is not a host:port address", prefix, s)
+			}
+		}
+	}
+
+	switch {
+	case c.Mnemonic != "":
+		checkMnemonic(check, prefix+"mnemonic", c.Mnemonic)
+		if c.WalletCount < 0 {
+			check.failf("%swallet-count %v is negative", prefix, c.WalletCount)
+		}
+	case c.PrivateKey != "":
+		if w := checkPrivateKey(check, prefix+"priv-key", c.PrivateKey); w != nil && c.PublicKey != "" {
+			checkPublicKey(check, prefix, c.PublicKey, w)
+		}
+	case !c.Simulate:
+		check.failf("%sno faucet wallet configured, set mnemonic or priv-key", prefix)
+	}
+
+	reserve := c.ReserveMnemonic != "" || c.ReservePrivateKey != ""
+	if c.ReserveMnemonic != "" {
+		checkMnemonic(check, prefix+"reserve-mnemonic", c.ReserveMnemonic)
+	} else if c.ReservePrivateKey != "" {
+		checkPrivateKey(check, prefix+"reserve-priv-key", c.ReservePrivateKey)
+	}
+	if reserve && (c.RefillThreshold == 0 || c.RefillAmount == 0) {
+		check.failf("%sa reserve wallet is configured but refill-threshold or refill-amount is 0, set both to enable refills", prefix)
+	}
+}
+
+func checkListenAddr(check *configCheck, key, addr string) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		check.failf("%v %q is not a listen address, e.g. \":8080\"", key, addr)
+	}
+}
+
+func checkMnemonic(check *configCheck, key, mnemonic string) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		check.failf("%v is not a valid bip39 mnemonic, check its words", key)
+	}
+}
+
+// checkPrivateKey returns the wallet of the hex encoded key, nil if it is invalid.
+func checkPrivateKey(check *configCheck, key, value string) *Wallet {
+	s := value
+	if len(s) > 1 && (s[0:2] == "0x" || s[0:2] == "0X") {
+		s = s[2:]
+	}
+	buf, err := hex.DecodeString(s)
+	if err != nil {
+		check.failf("%v is not hex encoded", key)
+		return nil
+	}
+	pk, err := NewPrivateKeyFromBuffer(buf)
+	if err != nil {
+		check.failf("%v is invalid: %v", key, err)
+		return nil
+	}
+	return NewWallet(pk)
+}
+
+// checkPublicKey checks that pub-key is the address of w, which is derived from priv-key.
+func checkPublicKey(check *configCheck, prefix, value string, w *Wallet) {
+	addr, err := gosmtypes.StringToAddress(value)
+	if err != nil {
+		check.failf("%spub-key %q is not an address: %v", prefix, value, err)
+		return
+	}
+	if !bytes.Equal(addr.Bytes(), w.Address.Bytes()) {
+		check.failf("%spub-key %v does not match priv-key, whose address is %v", prefix, addr.String(), w.Address.String())
+	}
+}
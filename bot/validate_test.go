@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func validConfig() BaseConfig {
+	cfg := testConfig()
+	cfg.Server = "localhost:9092"
+	cfg.Mnemonic = testMnemonic
+	cfg.BotToken = "token"
+	return cfg
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := testWallet(1)
+	cfg := validConfig()
+	cfg.Mnemonic = ""
+	cfg.PrivateKey = Bytes2Hex(w.Key)
+	cfg.PublicKey = w.Address.String()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("matching keys: %v", err)
+	}
+
+	cfg = BaseConfig{
+		PrivateKey:          "0x" + Bytes2Hex(w.Key),
+		PublicKey:           otherTestAddress,
+		LogLevel:            "loud",
+		HTTPForwardedHeader: "X-Forwarded-For",
+	}
+	err := cfg.Validate()
+	cfgErr, ok := err.(*ConfigError)
+	if !ok {
+		t.Fatalf("error = %v, want a *ConfigError", err)
+	}
+	// every problem is reported, not just the first
+	for _, want := range []string{"transfer-amount is 0", "cooldown is 0s", "no node configured", "pub-key", "log-level", "http-proxy-hops", "no frontend token"} {
+		found := false
+		for _, p := range cfgErr.Problems {
+			found = found || strings.Contains(p, want)
+		}
+		if !found {
+			t.Errorf("no %q problem in %v", want, cfgErr.Problems)
+		}
+	}
+}
+
+func TestValidateNetworks(t *testing.T) {
+	cfg := validConfig()
+	cfg.Networks = []NetworkConfig{
+		{Name: "devnet", Channels: []string{"c1"}},
+		{Name: "mainnet", Channels: []string{"c1"}, Mnemonic: "not a mnemonic"},
+		{Name: "devnet"},
+	}
+	err := cfg.Validate()
+	cfgErr, ok := err.(*ConfigError)
+	if !ok {
+		t.Fatalf("error = %v, want a *ConfigError", err)
+	}
+	if len(cfgErr.Problems) != 3 {
+		t.Errorf("problems = %q, want the duplicate name, channel and mnemonic", cfgErr.Problems)
+	}
+	for _, p := range cfgErr.Problems {
+		if !strings.HasPrefix(p, `network "`) {
+			t.Errorf("problem %q does not name its network", p)
+		}
+	}
+}
+
+func TestValidateSimulate(t *testing.T) {
+	// a simulated ledger needs neither a node nor keys
+	cfg := testConfig()
+	cfg.Simulate = true
+	cfg.TelegramToken = "token"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateFrontendToken(t *testing.T) {
+	cfg := validConfig()
+	cfg.BotToken = ""
+	err := cfg.Validate()
+	cfgErr, ok := err.(*ConfigError)
+	if !ok {
+		t.Fatalf("error = %v, want a *ConfigError", err)
+	}
+	if len(cfgErr.Problems) != 1 || !strings.Contains(cfgErr.Problems[0], "no frontend token configured") {
+		t.Errorf("problems = %q, want the missing token", cfgErr.Problems)
+	}
+	// the console needs no token
+	if err := cfg.ValidateConsole(); err != nil {
+		t.Error(err)
+	}
+}
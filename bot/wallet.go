@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/spacemeshos/ed25519"
+	gosmtypes "github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/tyler-smith/go-bip39"
+	"go.uber.org/zap"
+	"sync"
+)
+
+const spaceSalt = "Spacemesh blockmesh"
+
+// Wallet is a faucet hot wallet.
+type Wallet struct {
+	Address gosmtypes.Address
+	Key     ed25519.PrivateKey
+
+	// mu serializes transfers from the wallet so nonces are not reused
+	mu sync.Mutex
+	// inflight counts requests that picked the wallet and did not submit yet
+	inflight int
+}
+
+// NewWallet returns the wallet of key.
+func NewWallet(key ed25519.PrivateKey) *Wallet {
+	pub := key.Public().(ed25519.PublicKey)[:]
+	return &Wallet{Address: gosmtypes.BytesToAddress(pub), Key: key}
+}
+
+// DeriveWallets derives count wallets from mnemonic at indexes 0 to count-1.
+func DeriveWallets(mnemonic string, count int) []*Wallet {
+	if count < 1 {
+		count = 1
+	}
+	seed := bip39.NewSeed(mnemonic, "")
+	wallets := make([]*Wallet, 0, count)
+	for i := 0; i < count; i++ {
+		key := ed25519.NewDerivedKeyFromSeed(seed[:32], uint64(i), []byte(spaceSalt))
+		wallets = append(wallets, NewWallet(key))
+	}
+	return wallets
+}
+
+// WalletStatus is the state of a faucet wallet.
+type WalletStatus struct {
+	Address string `json:"address"`
+	Balance uint64 `json:"balance"`
+	// Pending is the number of transactions sent but not yet applied.
+	Pending uint64 `json:"pending"`
+}
+
+func (b *botBackend) walletStatus(node Client, w *Wallet) (*WalletStatus, error) {
+	account, err := node.AccountState(w.Address)
+	if err != nil {
+		return nil, err
+	}
+	st := &WalletStatus{
+		Address: w.Address.String(),
+		Balance: account.StateProjected.GetBalance().GetValue(),
+	}
+	if projected, current := account.StateProjected.GetCounter(), account.StateCurrent.GetCounter(); projected > current {
+		st.Pending = projected - current
+	}
+	return st, nil
+}
+
+// pickWallet selects the wallet to pay cost from: among wallets holding enough
+// funds the one with the fewest pending transactions, then the highest balance.
+// The returned wallet is reserved until release is called.
+func (b *botBackend) pickWallet(node Client, cost uint64) (w *Wallet, release func(), err error) {
+	statuses := make([]*WalletStatus, len(b.wallets))
+	for i, w := range b.wallets {
+		st, err := b.walletStatus(node, w)
+		if err != nil {
+			logger.Warn("failed to read faucet wallet", zap.String("wallet", w.Address.String()), zap.Error(err))
+			continue
+		}
+		statuses[i] = st
+	}
+
+	b.walletMu.Lock()
+	defer b.walletMu.Unlock()
+	best := -1
+	for i, st := range statuses {
+		if st == nil || st.Balance < cost {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		load := st.Pending + uint64(b.wallets[i].inflight)
+		bestLoad := statuses[best].Pending + uint64(b.wallets[best].inflight)
+		if load < bestLoad || (load == bestLoad && st.Balance > statuses[best].Balance) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, nil, fmt.Errorf("insufficient funds")
+	}
+
+	w = b.wallets[best]
+	w.inflight++
+	release = func() {
+		b.walletMu.Lock()
+		w.inflight--
+		b.walletMu.Unlock()
+	}
+	return w, release, nil
+}
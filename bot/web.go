@@ -0,0 +1,23 @@
+package bot
+
+import (
+	_ "embed"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+//go:embed web/index.html
+var webIndex []byte
+
+// serveIndex serves the faucet web page. The page uses the REST API so web
+// requests go through the same pipeline and cooldowns as every other frontend.
+func (f *HTTPFrontend) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(webIndex); err != nil {
+		logger.Warn("failed to write web page", zap.Error(err))
+	}
+}
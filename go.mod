@@ -5,10 +5,14 @@ go 1.16
 require (
 	github.com/bwmarrin/discordgo v0.23.2
 	github.com/nullstyle/go-xdr v0.0.0-20180726165426-f4c839f75077
+	github.com/prometheus/client_golang v0.9.3
 	github.com/spacemeshos/api/release/go v1.4.0
 	github.com/spacemeshos/ed25519 v0.0.0-20200604074309-d72da3b5f487
 	github.com/spacemeshos/go-spacemesh v0.1.45
 	github.com/spacemeshos/smrepl v0.1.32
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.4.0
 	github.com/tyler-smith/go-bip39 v1.1.0
+	go.uber.org/zap v1.16.0
+	google.golang.org/grpc v1.32.0
 )
@@ -2,102 +2,644 @@ package main
 
 import (
 	"bot/bot"
-	"flag"
+	"context"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
-	"github.com/spacemeshos/ed25519"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/smrepl/client"
+	"github.com/spf13/pflag"
 	"github.com/tyler-smith/go-bip39"
-	"log"
+	"go.uber.org/zap"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 )
 
-const spaceSalt = "Spacemesh blockmesh"
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	// configPollInterval is how often the config file is checked for changes
+	configPollInterval = 5 * time.Second
+)
+
+// logger is replaced by the configured one once the config is loaded.
+var logger, _ = bot.NewLogger("info", false)
 
 func main() {
-	// todo: read args - api address, account privatekey
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAudit(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+	args := os.Args[1:]
+	console := len(args) > 0 && args[0] == "console"
+	if console {
+		args = args[1:]
+	}
+	flags := bot.ConfigFlags("tapbot")
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		fmt.Printf("unexpected argument %v, usage:\n", flags.Arg(0))
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
 
-	cfg, err := bot.LoadConfigFromFile()
+	bot.SetLogger(logger)
+	cfg, err := bot.LoadConfigFromFile(flags)
 	if err != nil {
-		fmt.Println("error loading config from file ", err)
-		if cfg == nil {
-			cfg = bot.DefaultConfig()
+		logger.Error("error loading config", zap.Error(err))
+		os.Exit(1)
+	}
+	if err := validateConfig(cfg, console); err != nil {
+		logConfigError(err)
+		os.Exit(1)
+	}
+
+	if l, err := bot.NewLogger(cfg.LogLevel, cfg.LogJSON); err != nil {
+		logger.Error("error creating logger", zap.Error(err))
+	} else {
+		logger = l
+	}
+	defer logger.Sync()
+	bot.SetLogger(logger)
+
+	if cfg.DryRun {
+		logger.Info("dry run: fund requests are checked but no transfer is submitted")
+	}
+
+	// secrets are redacted by BaseConfig.String
+	logger.Info("loaded config", zap.Stringer("config", cfg))
+
+	var auditLog *bot.AuditLog
+	if cfg.AuditLog != "" {
+		auditLog, err = bot.OpenAuditLog(cfg.AuditLog)
+		if err != nil {
+			logger.Error("error opening audit log", zap.Error(err))
+			return
 		}
-		flag.StringVar(&cfg.Server, "server", "", fmt.Sprintf("The Spacemesh api grpc server host and port. Defaults to %s", cfg.Server))
-		flag.StringVar(&cfg.PublicKey, "public-key", "", "address of tap bot")
-		flag.StringVar(&cfg.PrivateKey, "private-key", "", "private key of tap (not needed if mnemonic provided)")
-		flag.StringVar(&cfg.Mnemonic, "mnemonic", "", "Mnemonic to recover keys from")
-		flag.Uint64Var(&cfg.TransferAmount, "amount", 10, "set the name of wallet file to open")
-		flag.StringVar(&cfg.Mnemonic, "wallet-directory", "", "set default wallet files directory")
-		flag.StringVar(&cfg.BotToken, "bot", "", "token for discord bot")
+		defer auditLog.Close()
 	}
 
+	var metrics *bot.Metrics
+	if cfg.MetricsListen != "" {
+		metrics = bot.NewMetrics()
+	}
 
+	var store *bot.StateStore
+	if cfg.StateFile != "" {
+		store, err = bot.OpenStateStore(cfg.StateFile)
+		if err != nil {
+			logger.Error("error opening state file", zap.Error(err))
+			return
+		}
+	}
 
-	//apiAddr := "127.0.0.1:9092"
-	//accountpk := ed25519.NewKeyFromSeed([]byte("somerandombytes"))
+	// every network has its own node, wallets and cooldowns, the router picks
+	// the network of a request by its --net argument or its channel
+	router := bot.NewNetworkRouter()
+	var networks []*network
+	for _, netCfg := range cfg.NetworkConfigs() {
+		n, err := openNetwork(netCfg, auditLog, metrics)
+		if err != nil {
+			logger.Error("error opening network", zap.String("network", netCfg.Network), zap.Error(err))
+			return
+		}
+		if st := store.Network(netCfg.Network); st != nil {
+			n.bot.Restore(st)
+			n.resume = st.Tracked
+			n.refills = st.Refills
+		}
+		router.Add(netCfg.Network, metrics.Handler(netCfg.Network, n.bot), netCfg.NetworkChannels)
+		networks = append(networks, n)
+	}
+	if metrics != nil {
+		if err := metrics.Start(cfg.MetricsListen); err != nil {
+			logger.Error("error starting metrics endpoint", zap.Error(err))
+			return
+		}
+		defer metrics.Close()
+		logger.Info("metrics served", zap.String("listen", cfg.MetricsListen))
+	}
+	// the gate stops new requests at shutdown and lets the ones in flight finish,
+	// the guild handler applies the settings of the discord servers
+	guilds := bot.NewGuildHandler(router, cfg.WithoutReserve(), store)
+	gate := bot.NewGate(guilds)
+	// the guild defaults and the api keys are replaced on a config reload
+	reloadables := []reloadable{guilds}
+	handler := bot.LogRequests(gate, cfg.LogMessages)
 
-	addr := types.Address{}
-	pk := ed25519.PrivateKey{}
-	if cfg.Mnemonic != "" {
-		seed := bip39.NewSeed(cfg.Mnemonic, "")
-		pk = ed25519.NewDerivedKeyFromSeed(seed[:32], 0, []byte(spaceSalt))
-		pub := pk.Public().(ed25519.PublicKey)[:]
-		addr = types.BytesToAddress(pub)
-	} else {
-		pk, err = bot.NewPrivateKeyFromBuffer(bot.FromHex(cfg.PrivateKey))
+	if console {
+		stop := make(chan struct{})
+		reload := make(chan struct{}, 1)
+		go watchConfig(cfg.ConfigFile, reload, stop)
+		go reloadConfig(flags, *cfg, router, networks, reloadables, console, reload, stop)
+		for _, n := range networks {
+			n.supervise(stop)
+		}
+		runConsole(handler, networks, reload)
+		close(stop)
+		saveState(store, networks)
+		return
+	}
+
+	// alerts go to discord if it is enabled, otherwise to telegram
+	var notifier bot.Notifier
+	// frontends are told about the payouts they requested
+	var listeners []bot.TxListener
+	// frontends showing the node connection state
+	var connListeners []bot.ConnListener
+	// frontends are closed at shutdown after the offline notice
+	var frontends []bot.Frontend
+
+	if cfg.BotToken != "" {
+		dg, err := discordgo.New("Bot " + cfg.BotToken)
 		if err != nil {
-			fmt.Println("no address provided")
+			logger.Error("error creating discord session", zap.Error(err))
 			return
 		}
-		pub := pk.Public().(ed25519.PublicKey)[:]
-		addr, err = types.StringToAddress(cfg.PublicKey)
+
+		// Register ready as a callback for the ready events.
+		dg.AddHandler(ready)
+
+		// The discord frontend registers its message handler and opens the websocket.
+		discord := bot.NewDiscordFrontend(dg)
+		discord.SetTxDMs(cfg.TxNotifyDM)
+		listeners = append(listeners, discord)
+		connListeners = append(connListeners, discord)
+		err = discord.Start(handler)
 		if err != nil {
-			fmt.Println("no public key found")
-			addr = types.BytesToAddress(pub)
+			logger.Error("error opening discord session", zap.Error(err))
+		}
+		notifier = discord
+		frontends = append(frontends, discord)
+	}
+
+	if cfg.HTTPListen != "" {
+		api := bot.NewHTTPFrontend(cfg.WithoutReserve())
+		if err := api.Start(handler); err != nil {
+			logger.Error("error starting http api", zap.Error(err))
+			return
 		}
+		frontends = append(frontends, api)
+		reloadables = append(reloadables, api)
+		logger.Info("http api listening", zap.String("listen", cfg.HTTPListen))
 	}
 
-	if cfg.BotToken == "" {
-		fmt.Println("No token provided. Please run: airhorn -t <bot token>")
+	if cfg.TelegramToken != "" {
+		telegram := bot.NewTelegramFrontend(cfg.WithoutReserve())
+		listeners = append(listeners, telegram)
+		if err := telegram.Start(handler); err != nil {
+			logger.Error("error starting telegram bot", zap.Error(err))
+			return
+		}
+		frontends = append(frontends, telegram)
+		if notifier == nil {
+			notifier = telegram
+		}
+	}
+
+	stop := make(chan struct{})
+
+	for _, n := range networks {
+		for _, l := range listeners {
+			n.tracker.AddListener(l)
+		}
+		if sup, ok := n.backend.(*bot.Supervisor); ok {
+			for _, l := range connListeners {
+				sup.AddListener(l)
+			}
+		}
+		n.tracker.Resume(n.resume)
+		n.run(notifier, stop)
+	}
+
+	exit := make(chan int)
+	reload := make(chan struct{}, 1)
+	go handleSignals(exit, reload, stop)
+	go watchConfig(cfg.ConfigFile, reload, stop)
+	go reloadConfig(flags, *cfg, router, networks, reloadables, false, reload, stop)
+	<-exit
+
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shutdown(ctx, cfg, gate, stop, networks, store, notifier, frontends)
+}
+
+// shutdown stops the bot in order: new requests are rejected, the requests in
+// flight are finished, the state is saved, operators are told the faucet goes
+// offline and the connections are closed. It gives up when ctx is done.
+func shutdown(ctx context.Context, cfg *bot.BaseConfig, gate *bot.Gate, stop chan struct{}, networks []*network, store *bot.StateStore, notifier bot.Notifier, frontends []bot.Frontend) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("shutting down")
+		gate.Close()
+		close(stop)
+		if err := gate.Wait(ctx); err != nil {
+			logger.Warn("requests still in flight at shutdown", zap.Error(err))
+		}
+
+		saveState(store, networks)
+		bot.NotifyOperators(notifier, cfg.WithoutReserve(), "🔌 The faucet is going offline")
+
+		for _, f := range frontends {
+			if err := f.Close(); err != nil {
+				logger.Warn("error closing frontend", zap.String("frontend", f.Name()), zap.Error(err))
+			}
+		}
+		for _, n := range networks {
+			if closer, ok := n.backend.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		logger.Info("shutdown complete")
+	case <-ctx.Done():
+		logger.Warn("shutdown deadline exceeded")
+	}
+}
+
+// saveState stops tracking payouts and saves the state of every network.
+func saveState(store *bot.StateStore, networks []*network) {
+	for _, n := range networks {
+		n.tracker.Close()
+		if store == nil {
+			continue
+		}
+		st := n.bot.State()
+		st.Tracked = n.tracker.Pending()
+		st.Refills = n.refills
+		if n.refiller != nil {
+			st.Refills = n.refiller.Refills()
+		}
+		store.SetNetwork(n.cfg.Network, st)
+	}
+	if store == nil {
 		return
 	}
+	if err := store.Save(); err != nil {
+		logger.Error("error saving state", zap.Error(err))
+	}
+}
+
+// faucetBot is the bot of a network.
+type faucetBot interface {
+	bot.Handler
+	SetConfig(cfg bot.BaseConfig)
+	State() *bot.NetworkState
+	Restore(st *bot.NetworkState)
+}
+
+// network is a faucet network served by the bot. client is the backend
+// measured by the metrics.
+type network struct {
+	cfg      bot.BaseConfig
+	backend  bot.Client
+	client   bot.Client
+	wallets  []*bot.Wallet
+	bot      faucetBot
+	reserve  *bot.Wallet
+	auditLog *bot.AuditLog
+	tracker  *bot.TxTracker
+	// resume are the txs tracked when the bot stopped
+	resume []bot.TrackedTx
+	// refills are the reserve refills of the last day when the bot stopped
+	refills  []bot.PayoutRecord
+	refiller *bot.Refiller
+}
+
+// openNetwork loads the wallets of a network, connects to its node and creates its bot.
+func openNetwork(cfg bot.BaseConfig, auditLog *bot.AuditLog, metrics *bot.Metrics) (*network, error) {
+	wallets, err := loadWallets(&cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	dg, err := discordgo.New("Bot " + cfg.BotToken)
+	be, err := openBackend(&cfg)
 	if err != nil {
-		fmt.Println("Error creating Discord session: ", err)
-		return
+		return nil, fmt.Errorf("error creating wallet backend: %v", err)
+	}
+
+	// the reserve wallet is only handed to the refiller, never to the bot
+	reserve, err := loadReserveWallet(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if sim, ok := be.(*bot.SimLedger); ok {
+		for _, w := range wallets {
+			sim.Fund(w.Address, cfg.SimBalance)
+		}
+		if reserve != nil {
+			sim.Fund(reserve.Address, cfg.SimBalance)
+		}
+	}
+
+	// node calls are measured, except the ones made when the metrics are scraped
+	client := metrics.Client(cfg.Network, be)
+	bb := bot.NewBot(client, wallets, cfg.WithoutReserve())
+	bb.SetAuditLog(auditLog)
+	tracker := bot.NewTxTracker(client, cfg.WithoutReserve())
+	bb.SetTracker(tracker)
+	if sup, ok := be.(*bot.Supervisor); ok {
+		sup.AddListener(bb)
+	}
+	metrics.AddNetwork(cfg.Network, be, wallets, tracker)
+	return &network{cfg: cfg, backend: be, client: client, wallets: wallets, bot: bb, reserve: reserve, auditLog: auditLog, tracker: tracker}, nil
+}
+
+// run starts the background tasks of the network until stop is closed.
+func (n *network) run(notifier bot.Notifier, stop chan struct{}) {
+	n.supervise(stop)
+
+	var addresses []types.Address
+	for _, w := range n.wallets {
+		addresses = append(addresses, w.Address)
+	}
+	monitor := bot.NewBalanceMonitor(n.client, addresses, notifier, n.cfg.WithoutReserve())
+	if monitor.Enabled() {
+		go monitor.Run(stop)
 	}
 
-	be, err := client.OpenConnection(cfg.Server, cfg.SecureConnection,"")
+	refiller := bot.NewRefiller(n.client, n.reserve, n.wallets, notifier, n.cfg)
+	refiller.SetAuditLog(n.auditLog)
+	refiller.Restore(n.refills)
+	n.refiller = refiller
+	if refiller.Enabled() {
+		go refiller.Run(stop)
+	}
+}
+
+// supervise starts reconnecting the node and checking the pool nodes until stop is closed.
+func (n *network) supervise(stop chan struct{}) {
+	if sup, ok := n.backend.(*bot.Supervisor); ok {
+		go sup.Run(stop)
+	}
+}
+
+// loadWallets returns the faucet wallets derived from the mnemonic, or the wallet of the private key.
+func loadWallets(cfg *bot.BaseConfig) ([]*bot.Wallet, error) {
+	if cfg.Mnemonic != "" {
+		return bot.DeriveWallets(cfg.Mnemonic, cfg.WalletCount), nil
+	}
+	if cfg.Simulate && cfg.PrivateKey == "" {
+		// throwaway wallets are good enough for a simulated ledger
+		entropy, err := bip39.NewEntropy(128)
+		if err != nil {
+			return nil, err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			return nil, err
+		}
+		return bot.DeriveWallets(mnemonic, cfg.WalletCount), nil
+	}
+
+	pk, err := bot.NewPrivateKeyFromBuffer(bot.FromHex(cfg.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("no address provided")
+	}
+	wallet := bot.NewWallet(pk)
+	if cfg.PublicKey != "" {
+		addr, err := types.StringToAddress(cfg.PublicKey)
+		if err != nil {
+			logger.Warn("invalid public key, using the address of the private key", zap.Error(err))
+		} else {
+			wallet.Address = addr
+		}
+	}
+	return []*bot.Wallet{wallet}, nil
+}
+
+// loadReserveWallet returns the reserve wallet used to refill the hot wallets, or nil if none is configured.
+func loadReserveWallet(cfg *bot.BaseConfig) (*bot.Wallet, error) {
+	if cfg.ReserveMnemonic != "" {
+		return bot.DeriveWallets(cfg.ReserveMnemonic, 1)[0], nil
+	}
+	if cfg.ReservePrivateKey == "" {
+		return nil, nil
+	}
+	pk, err := bot.NewPrivateKeyFromBuffer(bot.FromHex(cfg.ReservePrivateKey))
 	if err != nil {
-		fmt.Println("Error creating wallet backend: ", err)
+		return nil, fmt.Errorf("invalid reserve private key")
+	}
+	return bot.NewWallet(pk), nil
+}
+
+// openBackend connects to the configured node, or to a pool of nodes if several servers are configured.
+// The connection is supervised and dialed again when it breaks. A simulated ledger is used instead if
+// simulate is set.
+func openBackend(cfg *bot.BaseConfig) (bot.Client, error) {
+	if cfg.Simulate {
+		sim := bot.NewSimLedger(cfg.SimLayerDuration)
+		sim.SetFailRate(cfg.SimFailRate)
+		return sim, nil
+	}
+	dial := func(server string) (bot.Client, error) {
+		wb, err := client.OpenConnection(server, cfg.SecureConnection, "")
+		if err != nil {
+			return nil, err
+		}
+		// the wallet backend has no transaction state stream
+		return bot.NewStreamClient(wb, server, cfg.SecureConnection)
+	}
+	if len(cfg.Servers) == 0 {
+		return bot.NewSupervisor(cfg.Network, func() (bot.Client, error) {
+			return dial(cfg.Server)
+		}, cfg.NodeCheckInterval), nil
+	}
+	return bot.NewSupervisor(cfg.Network, func() (bot.Client, error) {
+		return bot.NewNodePool(cfg.Servers, cfg.NodeCheckInterval, dial)
+	}, cfg.NodeCheckInterval), nil
+}
+
+// runConsole serves commands from stdin until it is closed or the process is interrupted.
+func runConsole(h bot.Handler, networks []*network, reload chan struct{}) {
+	fmt.Println("tapbot console, type $help for the list of commands")
+	console := bot.NewConsoleFrontend(os.Stdin, os.Stdout)
+	for _, n := range networks {
+		n.tracker.AddListener(console)
+		n.tracker.Resume(n.resume)
+	}
+	if err := console.Start(h); err != nil {
+		logger.Error("error starting console", zap.Error(err))
 		return
 	}
 
-	bb := bot.NewBot(be, addr, pk, *cfg)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for {
+		select {
+		case <-console.Done():
+			return
+		case s := <-sigCh:
+			if s != syscall.SIGHUP {
+				return
+			}
+			triggerReload(reload)
+		}
+	}
+}
 
-	// Register ready as a callback for the ready events.
-	dg.AddHandler(ready)
+// validateConfig checks cfg, the console runs without a frontend token.
+func validateConfig(cfg *bot.BaseConfig, console bool) error {
+	if console {
+		return cfg.ValidateConsole()
+	}
+	return cfg.Validate()
+}
 
-	// Register messageCreate as a callback for the messageCreate events.
-	dg.AddHandler(bb.OnMessage)
+// logConfigError logs every problem found in the config.
+func logConfigError(err error) {
+	cfgErr, ok := err.(*bot.ConfigError)
+	if !ok {
+		logger.Error("invalid config", zap.Error(err))
+		return
+	}
+	for _, p := range cfgErr.Problems {
+		logger.Error("invalid config", zap.String("problem", p))
+	}
+}
 
-	// Open the websocket and begin listening.
-	err = dg.Open()
+// runConfig runs the config subcommands and returns the process exit code.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Println("usage: tapbot config check [flags]")
+		return 2
+	}
+	flags := bot.ConfigFlags("tapbot config check")
+	_ = flags.Parse(args[1:])
+	cfg, err := bot.LoadConfigFromFile(flags)
 	if err != nil {
-		fmt.Println("Error opening Discord session: ", err)
+		fmt.Println("Error loading config: ", err)
+		return 1
 	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	var names []string
+	for _, n := range cfg.NetworkConfigs() {
+		names = append(names, n.Network)
+	}
+	fmt.Printf("config OK, networks: %v\n", strings.Join(names, ", "))
+	return 0
+}
 
-	stop := make(chan struct{})
-	exit := make(chan int)
-	go handleSignals(exit, stop)
-	<-exit
+// runAudit runs the audit subcommands and returns the process exit code.
+func runAudit(args []string) int {
+	if len(args) != 2 || args[0] != "verify" {
+		fmt.Println("usage: tapbot audit verify <audit log file>")
+		return 2
+	}
+	f, err := os.Open(args[1])
+	if err != nil {
+		fmt.Println("Error opening audit log: ", err)
+		return 1
+	}
+	defer f.Close()
+
+	last, err := bot.VerifyAuditLog(f)
+	if err != nil {
+		fmt.Println("audit log verification FAILED: ", err)
+		return 1
+	}
+	if last == nil {
+		fmt.Println("audit log is empty")
+		return 0
+	}
+	fmt.Printf("audit log OK: %v entries, last hash %v\n", last.Seq, last.Hash)
+	return 0
+}
+
+// reloadable is a part of the bot taking the reloadable settings of a new config.
+type reloadable interface {
+	SetConfig(cfg bot.BaseConfig)
+}
+
+// reloadConfig loads the config again whenever reload fires, until stop is
+// closed, and applies the changes that do not need a restart to the networks
+// and to reloadables.
+func reloadConfig(flags *pflag.FlagSet, cfg bot.BaseConfig, router *bot.NetworkRouter, networks []*network, reloadables []reloadable, console bool, reload <-chan struct{}, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-reload:
+		}
+		next, err := bot.LoadConfigFromFile(flags)
+		if err != nil {
+			logger.Error("config reload failed, keeping the running config", zap.Error(err))
+			continue
+		}
+		if err := validateConfig(next, console); err != nil {
+			logConfigError(err)
+			logger.Error("config reload rejected, keeping the running config")
+			continue
+		}
+		applied, rejected := bot.ReloadConfig(cfg, *next)
+		for _, key := range rejected {
+			logger.Warn("config change rejected, restart to apply it", zap.String("key", key))
+		}
+		cfg = applied
+		for i, nc := range cfg.NetworkConfigs() {
+			networks[i].bot.SetConfig(nc.WithoutReserve())
+			router.SetChannels(nc.Network, nc.NetworkChannels)
+		}
+		for _, r := range reloadables {
+			r.SetConfig(cfg.WithoutReserve())
+		}
+		logger.Info("config reloaded", zap.Stringer("config", &cfg))
+	}
+}
 
+// watchConfig triggers a reload whenever the config file at path is modified,
+// until stop is closed.
+func watchConfig(path string, reload chan struct{}, stop <-chan struct{}) {
+	if path == "" {
+		return
+	}
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+	last := modTime()
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		// a file being replaced is missing for a moment
+		if t := modTime(); !t.IsZero() && !t.Equal(last) {
+			last = t
+			logger.Info("config file changed", zap.String("path", path))
+			triggerReload(reload)
+		}
+	}
+}
 
+// triggerReload asks for a reload unless one is pending already.
+func triggerReload(reload chan struct{}) {
+	select {
+	case reload <- struct{}{}:
+	default:
+	}
 }
 
 func ready(s *discordgo.Session, event *discordgo.Ready) {
@@ -105,33 +647,38 @@ func ready(s *discordgo.Session, event *discordgo.Ready) {
 	s.ChannelMessageSend("tap", "faucet bot ready")
 }
 
-func  handleSignals(exitCh chan int, stop chan struct{}) {
+func handleSignals(exitCh chan int, reload chan struct{}, stop chan struct{}) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(
 		sigCh,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT,
+		syscall.SIGHUP,
 	)
 
 	for {
 		select {
 		case <-stop:
-			log.Println("[INFO] stop handleSignals")
+			logger.Debug("stop handleSignals")
 			return
 		case s := <-sigCh:
 			switch s {
 			case syscall.SIGINT: // kill -SIGINT XXXX or Ctrl+c
-				log.Println("[SIGNAL] Catch SIGINT")
+				logger.Info("caught signal", zap.Stringer("signal", s))
 				exitCh <- 0
 
 			case syscall.SIGTERM: // kill -SIGTERM XXXX
-				log.Println("[SIGNAL] Catch SIGTERM")
+				logger.Info("caught signal", zap.Stringer("signal", s))
 				exitCh <- 1
 
 			case syscall.SIGQUIT: // kill -SIGQUIT XXXX
-				log.Println("[SIGNAL] Catch SIGQUIT")
+				logger.Info("caught signal", zap.Stringer("signal", s))
 				exitCh <- 0
+
+			case syscall.SIGHUP: // kill -SIGHUP XXXX
+				logger.Info("caught signal", zap.Stringer("signal", s))
+				triggerReload(reload)
 			}
 		}
 	}